@@ -0,0 +1,21 @@
+package browsh
+
+import "strings"
+
+// With --allowed-domains, pages can only be loaded from the listed domains, so that a
+// kiosk can't be used to browse anywhere else. The webextension does the blocking,
+// this is the same check for validating the flags. Listing a domain also allows its
+// subdomains.
+func isDomainAllowed(host, allowed string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, domain := range strings.Split(allowed, ",") {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" {
+			continue
+		}
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,141 @@
+package browsh
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gdamore/tcell"
+)
+
+// ALT+B shows how much data is flowing over the websocket between the browser and the
+// TTY, updated every second, for tuning things like the frame rate. What's written to
+// the terminal would be the better measure, but tcell opens /dev/tty itself and has no
+// way of wrapping what it writes to, so it isn't counted. So TTY-side settings, like
+// monochrome mode, don't change the reading, and over SSH it's local traffic rather
+// than what crosses the network. Alongside it are the frames rendered a second, the
+// zoom, where the page is scrolled or panned to, and the last key pressed, which help
+// when working out why a page isn't responding.
+type bandwidthMeter struct {
+	sync.Mutex
+	isVisible    bool
+	reading      string
+	lastFrom     int64
+	lastTo       int64
+	lastRendered int64
+	lastMeasured time.Time
+	lastKey      string
+	// So that only the latest updater keeps running when toggled quickly
+	generation int
+}
+
+var bandwidth = &bandwidthMeter{}
+
+func toggleBandwidthMeter() {
+	bandwidth.Lock()
+	bandwidth.isVisible = !bandwidth.isVisible
+	isVisible := bandwidth.isVisible
+	bandwidth.reading = "Measuring..."
+	bandwidth.lastFrom = atomic.LoadInt64(&stats.BytesFromBrowser)
+	bandwidth.lastTo = atomic.LoadInt64(&stats.BytesToBrowser)
+	bandwidth.lastRendered = atomic.LoadInt64(&stats.FramesRendered)
+	bandwidth.lastMeasured = time.Now()
+	bandwidth.generation++
+	generation := bandwidth.generation
+	bandwidth.Unlock()
+	if isVisible {
+		go updateBandwidthMeter(generation)
+	}
+	renderCurrentTabWindow()
+}
+
+func updateBandwidthMeter(generation int) {
+	defer recoverAndShutdown()
+	for {
+		time.Sleep(time.Second)
+		bandwidth.Lock()
+		if generation != bandwidth.generation {
+			bandwidth.Unlock()
+			return
+		}
+		from := atomic.LoadInt64(&stats.BytesFromBrowser)
+		to := atomic.LoadInt64(&stats.BytesToBrowser)
+		rendered := atomic.LoadInt64(&stats.FramesRendered)
+		seconds := time.Since(bandwidth.lastMeasured).Seconds()
+		bandwidth.reading = fmt.Sprintf("Browser traffic ↓ %s ↑ %s  %.0ffps",
+			formatBytesPerSecond(float64(from-bandwidth.lastFrom)/seconds),
+			formatBytesPerSecond(float64(to-bandwidth.lastTo)/seconds),
+			float64(rendered-bandwidth.lastRendered)/seconds)
+		if tab := CurrentTab; tab != nil {
+			bandwidth.reading += "  " + tabPositionReading(tab)
+		}
+		if bandwidth.lastKey != "" {
+			bandwidth.reading += "  " + bandwidth.lastKey
+		}
+		if !isConnectedToWebExtension {
+			bandwidth.reading = "Browser disconnected"
+		}
+		bandwidth.lastFrom = from
+		bandwidth.lastTo = to
+		bandwidth.lastRendered = rendered
+		bandwidth.lastMeasured = time.Now()
+		bandwidth.Unlock()
+		overlayBandwidthMeter()
+		screen.Show()
+	}
+}
+
+// Tabs start at Firefox's default zoom, 100%, until they're zoomed
+func tabPositionReading(t *tab) string {
+	zoom := t.zoom
+	if zoom == 0 {
+		zoom = 1
+	}
+	xScroll, yScroll := t.frame.viewport.position()
+	return fmt.Sprintf("zoom %.0f%%  col %d row %d", zoom*100, xScroll, yScroll)
+}
+
+func noteKeyPressForBandwidthMeter(ev *tcell.EventKey) {
+	// Anyone looking over the user's shoulder shouldn't be able to read their password
+	if isTypingPassword() {
+		return
+	}
+	bandwidth.Lock()
+	if bandwidth.isVisible {
+		bandwidth.lastKey = keyChordFromEvent(ev).String()
+	}
+	bandwidth.Unlock()
+}
+
+func formatBytesPerSecond(rate float64) string {
+	return formatBytes(rate) + "/s"
+}
+
+func formatBytes(size float64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1fMB", size/1024/1024)
+	case size >= 1024:
+		return fmt.Sprintf("%.1fKB", size/1024)
+	}
+	return fmt.Sprintf("%.0fB", size)
+}
+
+// Right aligned on the status line, so the page's status messages can still be seen
+func overlayBandwidthMeter() {
+	bandwidth.Lock()
+	isVisible := bandwidth.isVisible
+	reading := " " + bandwidth.reading + " "
+	bandwidth.Unlock()
+	if !isVisible || promptInputBox.isActive {
+		return
+	}
+	width, height := screen.Size()
+	x := width - len([]rune(reading))
+	style := tcell.StyleDefault.Reverse(true)
+	for _, c := range reading {
+		screen.SetContent(x, height-1, c, nil, style)
+		x++
+	}
+}
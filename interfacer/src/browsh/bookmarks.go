@@ -0,0 +1,95 @@
+package browsh
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Bookmarks are kept in bookmarks.json, in the data directory rather than next to the
+// config file, as Browsh writes them, not the user. ALT+D bookmarks the current page,
+// or removes its bookmark if it already has one, and ALT+R lists them.
+func bookmarksPath() string {
+	return filepath.Join(browshDataDir(), "bookmarks.json")
+}
+
+// There are no bookmarks until the first is saved
+func loadBookmarks(path string) ([]pageLink, error) {
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var bookmarks []pageLink
+	err = json.Unmarshal(contents, &bookmarks)
+	return bookmarks, err
+}
+
+func saveBookmarks(path string, bookmarks []pageLink) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	marshalled, _ := json.MarshalIndent(bookmarks, "", "  ")
+	return ioutil.WriteFile(path, append(marshalled, '\n'), 0644)
+}
+
+func toggleBookmark() {
+	if CurrentTab == nil {
+		return
+	}
+	bookmarks, err := loadBookmarks(bookmarksPath())
+	if err != nil {
+		logError("Couldn't load bookmarks: " + err.Error())
+		sendMessageToWebExtension("/status,Couldn't load bookmarks: " + err.Error())
+		return
+	}
+	message := "Bookmarked " + CurrentTab.Title
+	kept := withoutPageLink(bookmarks, CurrentTab.URI)
+	if len(kept) == len(bookmarks) {
+		kept = append(kept, pageLink{Title: CurrentTab.Title, URL: CurrentTab.URI})
+	} else {
+		message = "Removed the bookmark for " + CurrentTab.Title
+	}
+	if err := saveBookmarks(bookmarksPath(), kept); err != nil {
+		logError("Couldn't save bookmarks: " + err.Error())
+		message = "Couldn't save bookmarks: " + err.Error()
+	}
+	sendMessageToWebExtension("/status," + message)
+}
+
+func withoutPageLink(pages []pageLink, url string) []pageLink {
+	var kept []pageLink
+	for _, page := range pages {
+		if page.URL != url {
+			kept = append(kept, page)
+		}
+	}
+	return kept
+}
+
+func openBookmarkList() {
+	bookmarks, err := loadBookmarks(bookmarksPath())
+	if err != nil {
+		sendMessageToWebExtension("/status,Couldn't load bookmarks: " + err.Error())
+		return
+	}
+	if len(bookmarks) == 0 {
+		message := "No bookmarks yet"
+		if key := keyForAction("bookmark"); key != "" {
+			message += ", " + key + " bookmarks a page"
+		}
+		sendMessageToWebExtension("/status," + message)
+		return
+	}
+	openURLList("Bookmarks: ", bookmarks, func(removed pageLink) ([]pageLink, error) {
+		kept := withoutPageLink(bookmarks, removed.URL)
+		if err := saveBookmarks(bookmarksPath(), kept); err != nil {
+			return nil, err
+		}
+		bookmarks = kept
+		return kept, nil
+	})
+}
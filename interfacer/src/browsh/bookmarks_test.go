@@ -0,0 +1,89 @@
+package browsh
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBookmarks(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Bookmark tests")
+}
+
+var _ = Describe("Bookmarks", func() {
+	bookmarks := []pageLink{
+		{"Browsh", "https://www.brow.sh"},
+		{"Hacker News", "https://news.ycombinator.com"},
+		{"Wikipedia", "https://en.wikipedia.org"},
+	}
+
+	It("should match letters in order, closest together first", func() {
+		Expect(matchPageLinks(bookmarks, "")).To(Equal(bookmarks))
+		Expect(matchPageLinks(bookmarks, "WIKI")).To(Equal(bookmarks[2:]))
+		Expect(matchPageLinks(bookmarks, "or")).To(Equal([]pageLink{bookmarks[2], bookmarks[1], bookmarks[0]}))
+		Expect(matchPageLinks(bookmarks, "xyz")).To(BeEmpty())
+	})
+
+	It("should save and load bookmarks", func() {
+		dir, _ := ioutil.TempDir("", "browsh-bookmarks")
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "browsh", "bookmarks.json")
+		loaded, err := loadBookmarks(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(BeEmpty())
+		Expect(saveBookmarks(path, bookmarks)).To(Succeed())
+		Expect(loadBookmarks(path)).To(Equal(bookmarks))
+	})
+
+	It("should keep bookmarks in the XDG data directory", func() {
+		dataHome, hadDataHome := os.LookupEnv("XDG_DATA_HOME")
+		home := os.Getenv("HOME")
+		defer func() {
+			os.Setenv("HOME", home)
+			if hadDataHome {
+				os.Setenv("XDG_DATA_HOME", dataHome)
+			} else {
+				os.Unsetenv("XDG_DATA_HOME")
+			}
+		}()
+		os.Setenv("HOME", "/home/user")
+		os.Setenv("XDG_DATA_HOME", "/data")
+		Expect(bookmarksPath()).To(Equal(filepath.Join("/data", "browsh", "bookmarks.json")))
+		os.Setenv("XDG_DATA_HOME", "relative")
+		Expect(bookmarksPath()).To(Equal(
+			filepath.Join("/home/user", ".local", "share", "browsh", "bookmarks.json")))
+		os.Unsetenv("XDG_DATA_HOME")
+		Expect(bookmarksPath()).To(Equal(
+			filepath.Join("/home/user", ".local", "share", "browsh", "bookmarks.json")))
+	})
+})
+
+var _ = Describe("History", func() {
+	It("should keep each page once in the history, most recent first", func() {
+		defer func() { history.pages = nil }()
+		noteVisit("Browsh", "https://www.brow.sh")
+		noteVisit("New Tab", "about:blank")
+		noteVisit("Wikipedia", "https://en.wikipedia.org")
+		noteVisit("Browsh", "https://www.brow.sh")
+		Expect(history.pages).To(Equal([]pageLink{
+			{"Browsh", "https://www.brow.sh"},
+			{"Wikipedia", "https://en.wikipedia.org"},
+		}))
+	})
+
+	It("should scroll a long list to keep the selected page in view", func() {
+		start, end := dialogWindow(500, 0, 10)
+		Expect([]int{start, end}).To(Equal([]int{0, 10}))
+		start, end = dialogWindow(500, 100, 10)
+		Expect([]int{start, end}).To(Equal([]int{95, 105}))
+		start, end = dialogWindow(500, 499, 10)
+		Expect([]int{start, end}).To(Equal([]int{490, 500}))
+		start, end = dialogWindow(3, 2, 10)
+		Expect([]int{start, end}).To(Equal([]int{0, 3}))
+	})
+})
@@ -15,6 +15,11 @@ type cell struct {
 	bgColour  tcell.Color
 }
 
+func isSameCell(a, b cell) bool {
+	return a.fgColour == b.fgColour && a.bgColour == b.bgColour &&
+		string(a.character) == string(b.character)
+}
+
 // Both updating a frame and scrolling a frame can happen at the same time, so we need
 // to use mutexes.
 type threadSafeCellsMap struct {
@@ -0,0 +1,241 @@
+package browsh
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/gdamore/tcell"
+)
+
+// Firefox's certificate error page is hard to read at TTY resolutions and the
+// webextension APIs can't add exceptions from it anyway. So certificate errors are
+// shown in a dialog of Browsh's own, and exceptions are added through Firefox's
+// certificate override service. Accepting "once" lasts until Firefox is restarted,
+// accepting "always" is saved in the profile.
+type certError struct {
+	URL         string `json:"url"`
+	Error       string `json:"error"`
+	host        string
+	port        int
+	certificate *certDetails
+}
+
+type certDetails struct {
+	Subject     string `json:"subject"`
+	Issuer      string `json:"issuer"`
+	Fingerprint string `json:"fingerprint"`
+	Expires     string `json:"expires"`
+	Changed     bool   `json:"changed"`
+}
+
+// The certificate is fetched in the background whilst the dialog is showing, so the
+// dialog is guarded by a lock.
+var (
+	currentCertError *certError
+	certErrorLock    sync.Mutex
+)
+
+// The certificate of a connection that failed can't be got from the page, so connect
+// again in the background. `action` is one of "inspect", "once" or "always", and
+// `problem` is the kind of error that the override accepts, see certProblem(). The
+// new connection could be given a different certificate to the one the user
+// inspected, so nothing is accepted unless it has the `expected` fingerprint.
+const certOverrideScript = `
+	const [host, port, action, problem, expected] = arguments;
+	const resolve = arguments[arguments.length - 1];
+	const Ci = Components.interfaces;
+	const request = new XMLHttpRequest();
+	request.mozBackgroundRequest = true;
+	request.open("GET", "https://" + host + ":" + port + "/");
+	request.channel.loadFlags |= Ci.nsIRequest.LOAD_BYPASS_CACHE;
+	request.onloadend = () => {
+		const info = request.channel.securityInfo;
+		let cert;
+		try {
+			cert = info.QueryInterface(Ci.nsITransportSecurityInfo).serverCert;
+		} catch (e) {}
+		if (!cert) {
+			try {
+				cert = info.QueryInterface(Ci.nsISSLStatusProvider).SSLStatus.serverCert;
+			} catch (e) {}
+		}
+		if (!cert) {
+			resolve(null);
+			return;
+		}
+		if (action !== "inspect" && cert.sha256Fingerprint !== expected) {
+			resolve({ changed: true });
+			return;
+		}
+		if (action !== "inspect") {
+			const overrides = Components.classes["@mozilla.org/security/certoverride;1"]
+				.getService(Ci.nsICertOverrideService);
+			const problems = {
+				untrusted: overrides.ERROR_UNTRUSTED,
+				mismatch: overrides.ERROR_MISMATCH,
+				time: overrides.ERROR_TIME
+			};
+			let flags = problems[problem];
+			// Firefox only reports one error at a time, so a certificate with several
+			// problems is accepted once for each, and each replaces the last override.
+			try {
+				const existing = {};
+				if (overrides.hasMatchingOverride(host, port, cert, existing, {})) {
+					flags |= existing.value;
+				}
+			} catch (e) {}
+			overrides.rememberValidityOverride(host, port, cert, flags, action === "once");
+		}
+		resolve({
+			subject: cert.subjectName,
+			issuer: cert.issuerName,
+			fingerprint: cert.sha256Fingerprint,
+			expires: cert.validity.notAfterGMT
+		});
+	};
+	request.send();`
+
+func handleCertError(jsonString string) {
+	incoming := &certError{}
+	if err := json.Unmarshal([]byte(jsonString), incoming); err != nil {
+		Shutdown(err)
+	}
+	parsed, err := url.Parse(incoming.URL)
+	if err != nil {
+		return
+	}
+	// No key in kiosk mode can answer the dialog, and the public shouldn't be the ones
+	// deciding whether to trust a certificate anyway
+	if *isKioskMode {
+		sendMessageToWebExtension("/status,Rejected the certificate for " + parsed.Hostname())
+		return
+	}
+	incoming.host = parsed.Hostname()
+	incoming.port = 443
+	if parsed.Port() != "" {
+		fmt.Sscanf(parsed.Port(), "%d", &incoming.port)
+	}
+	certErrorLock.Lock()
+	currentCertError = incoming
+	certErrorLock.Unlock()
+	renderCurrentTabWindow()
+	go func() {
+		defer recoverAndShutdown()
+		details, err := runCertOverrideScript(incoming, "inspect", "")
+		if err != nil {
+			logError(err.Error())
+			return
+		}
+		certErrorLock.Lock()
+		incoming.certificate = details
+		certErrorLock.Unlock()
+		renderCurrentTabWindow()
+	}()
+}
+
+func isCertErrorShown() bool {
+	certErrorLock.Lock()
+	defer certErrorLock.Unlock()
+	return currentCertError != nil
+}
+
+// Accepting an expired certificate shouldn't also accept it for the wrong host, so
+// only the kind of problem in the error code that Firefox reported is overridden.
+func certProblem(errorCode string) string {
+	switch errorCode {
+	case "SSL_ERROR_BAD_CERT_DOMAIN":
+		return "mismatch"
+	case "SEC_ERROR_EXPIRED_CERTIFICATE", "SEC_ERROR_EXPIRED_ISSUER_CERTIFICATE",
+		"SEC_ERROR_INVALID_TIME", "MOZILLA_PKIX_ERROR_NOT_YET_VALID_CERTIFICATE",
+		"MOZILLA_PKIX_ERROR_NOT_YET_VALID_ISSUER_CERTIFICATE":
+		return "time"
+	}
+	return "untrusted"
+}
+
+func runCertOverrideScript(certErr *certError, action, expected string) (*certDetails, error) {
+	result, err := runFirefoxChromeScript(certOverrideScript,
+		[]interface{}{certErr.host, certErr.port, action, certProblem(certErr.Error), expected})
+	if err != nil {
+		return nil, err
+	}
+	if string(result) == "null" {
+		return nil, fmt.Errorf("Couldn't get the certificate for %s", certErr.host)
+	}
+	details := &certDetails{}
+	if err := json.Unmarshal(result, details); err != nil {
+		return nil, err
+	}
+	if details.Changed {
+		return nil, fmt.Errorf("The certificate for %s changed, so it wasn't accepted", certErr.host)
+	}
+	return details, nil
+}
+
+func handleCertErrorKeyPress(ev *tcell.EventKey) {
+	certErrorLock.Lock()
+	certErr := currentCertError
+	certificate := certErr.certificate
+	certErrorLock.Unlock()
+	// Until the certificate has loaded there's nothing the user has seen to accept
+	isLoaded := certificate != nil
+	switch {
+	case keyMap[keyChordFromEvent(ev)] == "quit":
+		quitBrowsh()
+		return
+	case ev.Rune() == 'o' && isLoaded:
+		acceptCertificate(certErr, "once", certificate.Fingerprint)
+	case ev.Rune() == 'a' && isLoaded:
+		acceptCertificate(certErr, "always", certificate.Fingerprint)
+	case ev.Rune() == 'r' || ev.Key() == tcell.KeyEscape:
+		sendMessageToWebExtension("/status,Rejected the certificate for " + certErr.host)
+	default:
+		return
+	}
+	certErrorLock.Lock()
+	currentCertError = nil
+	certErrorLock.Unlock()
+	renderCurrentTabWindow()
+}
+
+func acceptCertificate(certErr *certError, action, fingerprint string) {
+	go func() {
+		defer recoverAndShutdown()
+		if _, err := runCertOverrideScript(certErr, action, fingerprint); err != nil {
+			sendMessageToWebExtension("/status," + err.Error())
+			return
+		}
+		sendMessageToWebExtension("/url_bar," + certErr.URL)
+	}()
+}
+
+func certErrorDialogLines(certErr *certError) []string {
+	lines := []string{
+		"Certificate problem: " + certErr.Error,
+		fmt.Sprintf("Host: %s:%d", certErr.host, certErr.port),
+	}
+	if certErr.certificate == nil {
+		return append(lines, "Fetching the certificate...", "", "[r] Reject")
+	}
+	lines = append(lines,
+		"Subject: "+certErr.certificate.Subject,
+		"Issuer: "+certErr.certificate.Issuer,
+		"SHA-256: "+certErr.certificate.Fingerprint,
+		"Expires: "+certErr.certificate.Expires,
+	)
+	return append(lines, "", "[o] Accept once  [a] Always accept  [r] Reject")
+}
+
+// Drawn in a box in the middle of the focused pane, long lines are cut short
+func overlayCertErrorDialog() {
+	certErrorLock.Lock()
+	if currentCertError == nil {
+		certErrorLock.Unlock()
+		return
+	}
+	lines := certErrorDialogLines(currentCertError)
+	certErrorLock.Unlock()
+	overlayDialog(lines, -1)
+}
@@ -0,0 +1,81 @@
+package browsh
+
+import (
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// Everything copied in this session, most recent first, each only once. The terminal's
+// clipboard only holds the last thing copied, so ALT+SHIFT+C lists the rest, and ENTER
+// copies the selected one again, pasting it too if an input box is focused.
+const maxClipboardHistoryLength = 50
+
+var clipboardHistory struct {
+	entries   []string
+	isVisible bool
+	selected  int
+}
+
+func noteCopy(text string) {
+	if text == "" {
+		return
+	}
+	entries := []string{text}
+	for _, entry := range clipboardHistory.entries {
+		if entry != text {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) > maxClipboardHistoryLength {
+		entries = entries[:maxClipboardHistoryLength]
+	}
+	clipboardHistory.entries = entries
+}
+
+func openClipboardHistory() {
+	if len(clipboardHistory.entries) == 0 {
+		sendMessageToWebExtension("/status," + nothingCopiedMessage())
+		return
+	}
+	clipboardHistory.isVisible = true
+	clipboardHistory.selected = 0
+	renderCurrentTabWindow()
+}
+
+func handleClipboardHistoryKeyPress(ev *tcell.EventKey) {
+	count := len(clipboardHistory.entries)
+	switch ev.Key() {
+	case tcell.KeyUp:
+		clipboardHistory.selected = (clipboardHistory.selected - 1 + count) % count
+	case tcell.KeyDown:
+		clipboardHistory.selected = (clipboardHistory.selected + 1) % count
+	case tcell.KeyEnter:
+		clipboardHistory.isVisible = false
+		copyToClipboard(clipboardHistory.entries[clipboardHistory.selected])
+		if activeInputBox != nil && !isUIInputBoxActive() {
+			pasteIntoInputBox()
+		} else {
+			sendMessageToWebExtension("/status,Copied " + clipboardHistoryLine(lastCopiedText))
+		}
+	case tcell.KeyEscape:
+		clipboardHistory.isVisible = false
+	}
+	renderCurrentTabWindow()
+}
+
+// Copies can span lines, but each is listed on one
+func clipboardHistoryLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func overlayClipboardHistory() {
+	if !clipboardHistory.isVisible {
+		return
+	}
+	lines := make([]string, len(clipboardHistory.entries))
+	for i, entry := range clipboardHistory.entries {
+		lines[i] = clipboardHistoryLine(entry)
+	}
+	overlayListDialog(nil, lines, clipboardHistory.selected)
+}
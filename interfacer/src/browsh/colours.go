@@ -0,0 +1,77 @@
+package browsh
+
+import (
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+var colourModes = map[string]string{
+	"truecolor": "xterm-truecolor",
+	"256":       "xterm-256color",
+	"16":        "xterm-16color",
+	"8":         "xterm",
+}
+
+// The $TERM that tcell didn't know, if it had to fall back from one. Logging isn't
+// set up yet when the screen is made, so it's logged along with the colours chosen.
+var unknownTerm string
+
+// Tcell picks the colours it renders with from the terminfo entry for $TERM, fitting
+// each of Browsh's RGB colours to the nearest one in the terminal's palette when it
+// can't do true colour. Many terminals support true colour without their terminfo
+// entry saying so though, so $COLORTERM is checked as well, as it's the usual way for
+// terminals to announce it. SSH doesn't pass $COLORTERM on by default, so remote
+// sessions usually get the colours of their $TERM. GNU screen ($STY) keeps the
+// $COLORTERM of the terminal it was started in, but only passes 256 colours through
+// to it. Tmux sets its own $TERM, and tcell uses SGR mouse reporting, which tmux
+// passes on when its `mouse` option is on, so it needs nothing special.
+func setupColourMode() (tcell.Screen, error) {
+	// On Windows tcell uses the console API rather than $TERM
+	if runtime.GOOS == "windows" {
+		return tcell.NewScreen()
+	}
+	if term, ok := colourModes[*colourMode]; ok {
+		os.Setenv("TERM", term)
+	} else if isTrueColourSession(os.Getenv("TERM"), os.Getenv("COLORTERM"), os.Getenv("STY")) {
+		os.Setenv("TERM", colourModes["truecolor"])
+	}
+	screen, err := tcell.NewScreen()
+	if err == nil || *colourMode != "auto" {
+		return screen, err
+	}
+	// Tcell's terminfo database doesn't know every terminal
+	unknownTerm = os.Getenv("TERM")
+	for _, mode := range colourModeFallbacks(unknownTerm) {
+		os.Setenv("TERM", colourModes[mode])
+		if screen, err = tcell.NewScreen(); err == nil {
+			break
+		}
+	}
+	return screen, err
+}
+
+func isTrueColourSession(term, colorterm, sty string) bool {
+	return sty == "" && isTrueColourTerminal(term, colorterm)
+}
+
+// A terminal that says it has 256 colours most likely does, whatever else it is.
+// Otherwise 16 colours is the last resort, as nearly every terminal has them. Tcell
+// only knows xterm-16color from the system's terminfo database though, so the 8 of
+// plain xterm, which it always knows, are tried after that.
+func colourModeFallbacks(term string) []string {
+	if strings.Contains(term, "256color") {
+		return []string{"256", "16", "8"}
+	}
+	return []string{"16", "8"}
+}
+
+func isTrueColourTerminal(term, colorterm string) bool {
+	colorterm = strings.ToLower(colorterm)
+	if colorterm == "truecolor" || colorterm == "24bit" {
+		return true
+	}
+	return strings.HasSuffix(term, "-truecolor") || strings.HasSuffix(term, "-direct")
+}
@@ -0,0 +1,164 @@
+package browsh
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestColours(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Colour tests")
+}
+
+var _ = Describe("Colour modes", func() {
+	It("should believe terminals that announce true colour", func() {
+		Expect(isTrueColourTerminal("xterm-256color", "truecolor")).To(BeTrue())
+		Expect(isTrueColourTerminal("xterm-256color", "24bit")).To(BeTrue())
+		Expect(isTrueColourTerminal("xterm-direct", "")).To(BeTrue())
+	})
+
+	It("should leave other terminals to their terminfo", func() {
+		Expect(isTrueColourTerminal("xterm-256color", "")).To(BeFalse())
+		Expect(isTrueColourTerminal("screen", "")).To(BeFalse())
+	})
+
+	It("should not trust $COLORTERM inside GNU screen", func() {
+		Expect(isTrueColourSession("screen", "truecolor", "")).To(BeTrue())
+		Expect(isTrueColourSession("screen", "truecolor", "1234.pts-0.host")).To(BeFalse())
+	})
+
+	It("should fall back to 16 colours for unknown terminals", func() {
+		Expect(colourModeFallbacks("foot")).To(Equal([]string{"16", "8"}))
+		Expect(colourModeFallbacks("foot-256color")).To(Equal([]string{"256", "16", "8"}))
+	})
+})
+
+var _ = Describe("Render modes", func() {
+	text := cell{
+		fgColour:  tcell.NewRGBColor(200, 200, 200),
+		bgColour:  tcell.NewRGBColor(220, 220, 220),
+		character: []rune("a"),
+	}
+	pixels := cell{
+		fgColour:  tcell.NewRGBColor(10, 230, 10),
+		bgColour:  tcell.NewRGBColor(10, 10, 200),
+		character: []rune("▄"),
+	}
+
+	AfterEach(func() {
+		RenderMode = "colour"
+	})
+
+	It("should swap every colour for its opposite when inverted", func() {
+		RenderMode = "inverted"
+		fg, bg, character := renderModeCell(pixels)
+		Expect(fg).To(Equal(tcell.NewRGBColor(245, 25, 245)))
+		Expect(bg).To(Equal(tcell.NewRGBColor(245, 245, 55)))
+		Expect(character).To(Equal('▄'))
+	})
+
+	It("should keep text readable against its background in high contrast", func() {
+		RenderMode = "high-contrast"
+		fg, bg, _ := renderModeCell(text)
+		Expect(fg).To(Equal(tcell.ColorBlack))
+		Expect(bg).To(Equal(tcell.ColorWhite))
+	})
+
+	It("should split graphics at the contrast threshold", func() {
+		RenderMode = "high-contrast"
+		fg, bg, _ := renderModeCell(pixels)
+		Expect(fg).To(Equal(tcell.ColorWhite))
+		Expect(bg).To(Equal(tcell.ColorBlack))
+	})
+
+	It("should cycle back round to colour", func() {
+		for range renderModes {
+			cycleRenderMode()
+		}
+		Expect(RenderMode).To(Equal("colour"))
+	})
+})
+
+var _ = Describe("Dithering", func() {
+	// A red three quarters of the way from black to maroon in the 8 colour palette
+	red := []int32{}
+	for i := 0; i < 16; i++ {
+		red = append(red, 96, 0, 0)
+	}
+
+	AfterEach(func() {
+		*ditherMode = "none"
+	})
+
+	countMaroons := func(colours []tcell.Color) int {
+		maroons := 0
+		for _, colour := range colours {
+			Expect(colour).To(Or(Equal(tcell.ColorBlack), Equal(tcell.ColorMaroon)))
+			if colour == tcell.ColorMaroon {
+				maroons++
+			}
+		}
+		return maroons
+	}
+
+	It("should leave colours alone without a palette to dither to", func() {
+		*ditherMode = "ordered"
+		colours := pixelColours(red, 4, 4, 0, 0, 0)
+		Expect(colours[0]).To(Equal(tcell.NewRGBColor(96, 0, 0)))
+	})
+
+	It("should mix palette colours with an ordered pattern", func() {
+		*ditherMode = "ordered"
+		colours := pixelColours(red, 4, 4, 0, 0, 8)
+		Expect(countMaroons(colours)).To(BeNumerically("~", 12, 2))
+		Expect(pixelColours(red, 4, 4, 1, 0, 8)[0]).To(Equal(colours[1]))
+	})
+
+	It("should spread the error with Floyd-Steinberg", func() {
+		*ditherMode = "floyd-steinberg"
+		Expect(countMaroons(pixelColours(red, 4, 4, 0, 0, 8))).To(BeNumerically("~", 12, 2))
+	})
+})
+
+var _ = Describe("Picture settings", func() {
+	AfterEach(func() {
+		*brightness, *contrast, *gamma = 0, 1, 1
+		setupPicture()
+	})
+
+	It("should leave colours alone by default", func() {
+		setupPicture()
+		Expect(adjustedRGBColour(20, 30, 40)).To(Equal(tcell.NewRGBColor(20, 30, 40)))
+	})
+
+	It("should brighten every channel", func() {
+		*brightness = 20
+		setupPicture()
+		Expect(adjustPicture([]int32{0, 100, 250})).To(Equal([]int32{51, 151, 255}))
+	})
+
+	It("should spread colours away from the middle with more contrast", func() {
+		*contrast = 1.5
+		setupPicture()
+		Expect(adjustPicture([]int32{20, 100, 200})).To(Equal([]int32{0, 86, 236}))
+	})
+
+	It("should lift dark colours more than light ones with more gamma", func() {
+		*gamma = 2
+		setupPicture()
+		Expect(adjustPicture([]int32{0, 64, 255})).To(Equal([]int32{0, 128, 255}))
+	})
+
+	It("should step back to no change", func() {
+		setupPicture()
+		for i := 0; i < 3; i++ {
+			changePicture("contrast-up")
+		}
+		changePicture("picture-reset")
+		Expect(pictureSettings.isAdjusted).To(BeFalse())
+	})
+})
@@ -0,0 +1,42 @@
+package browsh
+
+import (
+	"github.com/gdamore/tcell"
+)
+
+// Browsh's own keys are mostly ALT combinations, which some terminals and window
+// managers keep for themselves, and which pages sometimes want too. In command mode
+// keys control Browsh rather than the page, a bit like tmux's prefix key. Each key runs
+// the action it has with ALT held, eg; Q shows the QR code, unless it has an action of
+// its own, like TAB. ESC, or the command mode key again, goes back to sending keys to
+// the page.
+var isCommandMode = false
+
+const commandModeLabel = " COMMAND "
+
+func toggleCommandMode() {
+	isCommandMode = !isCommandMode
+	renderCurrentTabWindow()
+}
+
+func handleCommandModeKeyPress(ev *tcell.EventKey) {
+	chord := keyChordFromEvent(ev)
+	action := commandModeAction(chord)
+	if ev.Key() == tcell.KeyEscape || action == "command-mode" {
+		toggleCommandMode()
+		return
+	}
+	if action == "" {
+		sendMessageToWebExtension("/status,Nothing is on " + chord.String() + " in command mode, ESC leaves it")
+		return
+	}
+	runKeyAction(action)
+}
+
+func commandModeAction(chord keyChord) string {
+	if action, ok := keyMap[chord]; ok {
+		return action
+	}
+	chord.modifiers |= tcell.ModAlt
+	return keyMap[chord]
+}
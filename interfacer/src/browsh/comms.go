@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -25,9 +26,11 @@ type incomingRawText struct {
 }
 
 func startWebSocketServer() {
+	defer recoverAndShutdown()
 	serverMux := http.NewServeMux()
 	serverMux.HandleFunc("/", webSocketServer)
-	if err := http.ListenAndServe(":"+*webSocketPort, serverMux); err != nil {
+	// Only Firefox on this machine should be able to pretend to be the webextension
+	if err := http.ListenAndServe("127.0.0.1:"+*webSocketPort, serverMux); err != nil {
 		Shutdown(err)
 	}
 }
@@ -37,19 +40,25 @@ func sendMessageToWebExtension(message string) {
 		Log("Webextension not connected. Message not sent: " + message)
 		return
 	}
+	countStat(&stats.BytesToBrowser, len(message))
 	stdinChannel <- message
 }
 
 // Listen to all messages coming from the webextension
 // TODO: It seems this *also* receives sent to the webextention!?
 func webSocketReader(ws *websocket.Conn) {
+	defer recoverAndShutdown()
 	defer ws.Close()
 	for {
 		_, message, err := ws.ReadMessage()
+		countStat(&stats.BytesFromBrowser, len(message))
 		handleWebextensionCommand(message)
 		if err != nil {
-			if websocket.IsCloseError(err, websocket.CloseGoingAway) {
+			// Abnormal closures are from Firefox stopping without closing the websocket first
+			if websocket.IsCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				Log("Socket reader detected that the browser closed the websocket")
+				isConnectedToWebExtension = false
+				fireSessionEvent("browser_disconnected", nil)
 				triggerSocketWriterClose()
 				return
 			}
@@ -67,18 +76,48 @@ func handleWebextensionCommand(message []byte) {
 	}
 	switch command {
 	case "/frame_text":
-		parseJSONFrameText(strings.Join(parts[1:], ","))
-		renderCurrentTabWindow()
+		start := time.Now()
+		tabID := parseJSONFrameText(strings.Join(parts[1:], ","))
+		frameParsingHistogram.observeSince(start)
+		if !isCapturePaused {
+			renderCurrentTabWindow()
+		}
+		maybeMeasureLatency(tabID)
 	case "/frame_pixels":
-		parseJSONFramePixels(strings.Join(parts[1:], ","))
-		renderCurrentTabWindow()
+		start := time.Now()
+		tabID := parseJSONFramePixels(strings.Join(parts[1:], ","))
+		frameParsingHistogram.observeSince(start)
+		if !isCapturePaused {
+			renderCurrentTabWindow()
+		}
+		maybeMeasureLatency(tabID)
 	case "/tab_state":
 		parseJSONTabState(strings.Join(parts[1:], ","))
 		if CurrentTab != nil {
 			renderUI()
 		}
+	case "/hello":
+		handleHello(strings.Join(parts[1:], ","))
 	case "/screenshot":
 		saveScreenshot(parts[1])
+	case "/file_input":
+		handleFileInputRequest(strings.Join(parts[1:], ","))
+	case "/auth_required":
+		handleAuthRequest(strings.Join(parts[1:], ","))
+	case "/cert_error":
+		handleCertError(strings.Join(parts[1:], ","))
+	case "/download":
+		handleDownload(strings.Join(parts[1:], ","))
+	case "/find_result":
+		handleFindResult(parts[1])
+	case "/focus":
+		handleFocus(parts[1], parts[2])
+	case "/paused":
+		handlePaused(parts[1])
+	case "/zoomed":
+		handleZoomed(parts[1], parts[2])
+	case "/paragraphs":
+		handleParagraphs(strings.Join(parts[1:], ","))
 	default:
 		Log("WEBEXT: " + string(message))
 	}
@@ -94,7 +133,7 @@ func handleRawFrameTextCommands(parts []string) {
 		}
 		if incoming.RequestID != "" {
 			Log("Raw text for " + incoming.RequestID)
-			rawTextRequests[incoming.RequestID] = incoming.RawText
+			rawTextRequests.store(incoming.RequestID, incoming.RawText)
 		} else {
 			Log("Raw text but no associated request ID")
 		}
@@ -116,12 +155,13 @@ func triggerSocketWriterClose() {
 // Send a message to the webextension
 func webSocketWriter(ws *websocket.Conn) {
 	var message string
+	defer recoverAndShutdown()
 	defer ws.Close()
 	for {
 		message = <-stdinChannel
 		Log(fmt.Sprintf("TTY sending: %s", message))
 		if err := ws.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
-			if err == websocket.ErrCloseSent {
+			if err == websocket.ErrCloseSent || !isConnectedToWebExtension {
 				Log("Socket writer detected that the browser closed the websocket")
 				return
 			}
@@ -137,13 +177,17 @@ func webSocketServer(w http.ResponseWriter, r *http.Request) {
 		Shutdown(err)
 	}
 	isConnectedToWebExtension = true
+	fireSessionEvent("browser_connected", nil)
 	go webSocketWriter(ws)
 	go webSocketReader(ws)
 	if *IsHTTPServer {
 		sendMessageToWebExtension("/raw_text_mode")
+		notifySystemd("READY=1")
 	} else {
 		sendTtySize()
+		startRecordingAndReplay()
 	}
+	go expectHello()
 	// For some reason, using Firefox's CLI arg `--url https://google.com` doesn't consistently
 	// work. So we do it here instead.
 	sendMessageToWebExtension("/new_tab," + *StartupURL)
@@ -0,0 +1,174 @@
+package browsh
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/shibukawa/configdir"
+)
+
+// Settings can also be kept in a TOML file, eg; `~/.config/browsh/config.toml` on
+// Linux. Its keys are the same as the command line flags, and flags given on the
+// command line take precedence over the file. Keys in a [section] are prefixed with the
+// section's name, so these are the same:
+//
+//	webhook-url = "https://example.com/hook"
+//
+//	[webhook]
+//	url = "https://example.com/hook"
+//
+// Only the parts of TOML that flags need are supported: strings, numbers and booleans.
+//
+// A different file can be given with --config, in which case it has to exist.
+func getConfigFilePath() (string, bool) {
+	if path := configFileFromArgs(os.Args[1:]); path != "" {
+		return path, true
+	}
+	return filepath.Join(browshConfigDir(), "config.toml"), false
+}
+
+func browshConfigDir() string {
+	return configdir.New("browsh", "").QueryFolders(configdir.Global)[0].Path
+}
+
+// Where Browsh keeps what it saves for the user, like bookmarks. XDG_DATA_HOME is
+// ignored unless it's absolute, as the XDG spec says, leaving ~/.local/share.
+func browshDataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "browsh")
+	}
+	if home := os.Getenv("HOME"); home != "" {
+		return filepath.Join(home, ".local", "share", "browsh")
+	}
+	return browshConfigDir()
+}
+
+// The file is loaded before the flags are parsed, so that they can override it, so
+// --config has to be found by hand.
+func configFileFromArgs(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == "config" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(name, "config=") {
+			return strings.TrimPrefix(name, "config=")
+		}
+	}
+	return ""
+}
+
+// Missing config files are fine, it's optional
+func loadConfigFile(path string) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	settings, err := parseConfig(bufio.NewScanner(file))
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	for _, setting := range settings {
+		if flag.Lookup(setting.key) == nil {
+			return fmt.Errorf("%s:%d: there's no setting called '%s'", path, setting.line, setting.key)
+		}
+		if err := flag.Set(setting.key, setting.value); err != nil {
+			return fmt.Errorf("%s:%d: %s", path, setting.line, err)
+		}
+	}
+	return nil
+}
+
+type configSetting struct {
+	key   string
+	value string
+	line  int
+}
+
+func parseConfig(scanner *bufio.Scanner) ([]configSetting, error) {
+	var settings []configSetting
+	section := ""
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			end := strings.Index(line, "]")
+			if end < 0 || !isConfigComment(line[end+1:]) {
+				return nil, fmt.Errorf("line %d: sections look like [name]", lineNumber)
+			}
+			section = strings.TrimSpace(line[1:end])
+			continue
+		}
+		equals := strings.Index(line, "=")
+		if equals < 1 {
+			return nil, fmt.Errorf("line %d: settings look like key = value", lineNumber)
+		}
+		key := strings.Trim(strings.TrimSpace(line[:equals]), `"`)
+		if section != "" {
+			key = section + "-" + key
+		}
+		value, err := parseConfigValue(strings.TrimSpace(line[equals+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNumber, err)
+		}
+		settings = append(settings, configSetting{key: key, value: value, line: lineNumber})
+	}
+	return settings, scanner.Err()
+}
+
+func isConfigComment(text string) bool {
+	text = strings.TrimSpace(text)
+	return text == "" || strings.HasPrefix(text, "#")
+}
+
+func parseConfigValue(text string) (string, error) {
+	switch {
+	case strings.HasPrefix(text, `"`):
+		end := 1
+		for ; end < len(text); end++ {
+			if text[end] == '\\' {
+				end++
+				continue
+			}
+			if text[end] == '"' {
+				break
+			}
+		}
+		if end >= len(text) || !isConfigComment(text[end+1:]) {
+			return "", fmt.Errorf("unterminated string %s", text)
+		}
+		return strconv.Unquote(text[:end+1])
+	case strings.HasPrefix(text, "'"):
+		end := strings.Index(text[1:], "'")
+		if end < 0 || !isConfigComment(text[end+2:]) {
+			return "", fmt.Errorf("unterminated string %s", text)
+		}
+		return text[1 : end+1], nil
+	}
+	if comment := strings.Index(text, "#"); comment >= 0 {
+		text = strings.TrimSpace(text[:comment])
+	}
+	if text == "true" || text == "false" {
+		return text, nil
+	}
+	if _, err := strconv.ParseFloat(strings.Replace(text, "_", "", -1), 64); err == nil {
+		return strings.Replace(text, "_", "", -1), nil
+	}
+	return "", fmt.Errorf("'%s' isn't a string, number or boolean, strings need quotes", text)
+}
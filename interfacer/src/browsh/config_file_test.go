@@ -0,0 +1,75 @@
+package browsh
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestConfigFile(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Config file tests")
+}
+
+func parseConfigString(config string) ([]configSetting, error) {
+	return parseConfig(bufio.NewScanner(strings.NewReader(config)))
+}
+
+var _ = Describe("Config files", func() {
+	It("should parse strings, numbers and booleans", func() {
+		settings, err := parseConfigString(`
+# Comments are ignored
+startup-url = "https://www.brow.sh/#top" # Even after values
+time-limit = 1_000
+with-gui = true
+screenshot-upload-command = 'scp {file} me@example.com:'
+mqtt-topic = "kiosks/\"lobby\""
+`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(settings).To(Equal([]configSetting{
+			{key: "startup-url", value: "https://www.brow.sh/#top", line: 3},
+			{key: "time-limit", value: "1000", line: 4},
+			{key: "with-gui", value: "true", line: 5},
+			{key: "screenshot-upload-command", value: "scp {file} me@example.com:", line: 6},
+			{key: "mqtt-topic", value: `kiosks/"lobby"`, line: 7},
+		}))
+	})
+
+	It("should prefix keys with their section", func() {
+		settings, _ := parseConfigString("[webhook]\nurl = \"https://example.com/hook\"")
+		Expect(settings[0].key).To(Equal("webhook-url"))
+	})
+
+	It("should say which line is wrong", func() {
+		_, err := parseConfigString("time-limit = 10\nstartup-url = https://www.brow.sh")
+		Expect(err).To(MatchError(ContainSubstring("line 2: 'https://www.brow.sh' isn't a string")))
+	})
+
+	It("should set flags, and reject unknown ones", func() {
+		dir, _ := ioutil.TempDir("", "browsh-config")
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "config.toml")
+		ioutil.WriteFile(path, []byte("[mqtt]\ntopic = \"lobby\"\n"), 0644)
+		Expect(loadConfigFile(path)).To(Succeed())
+		Expect(*mqttTopic).To(Equal("lobby"))
+		*mqttTopic = "browsh"
+		ioutil.WriteFile(path, []byte("teleport = true\n"), 0644)
+		Expect(loadConfigFile(path)).To(MatchError(ContainSubstring(":1: there's no setting called 'teleport'")))
+	})
+
+	It("should be fine without a config file", func() {
+		Expect(loadConfigFile("/no/such/config.toml")).To(Succeed())
+	})
+	It("should find --config before the flags are parsed", func() {
+		Expect(configFileFromArgs([]string{"--debug", "--config", "a.toml"})).To(Equal("a.toml"))
+		Expect(configFileFromArgs([]string{"-config=b.toml", "doctor"})).To(Equal("b.toml"))
+		Expect(configFileFromArgs([]string{"--startup-url", "https://brow.sh", "--config", "c.toml"})).To(Equal("c.toml"))
+		Expect(configFileFromArgs([]string{"--", "--config", "d.toml"})).To(Equal(""))
+	})
+})
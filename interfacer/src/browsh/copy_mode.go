@@ -0,0 +1,169 @@
+package browsh
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// ALT+C starts copy mode, which is like Vim's visual mode. The arrow keys, or H, J, K
+// and L, move a cursor over the page, V starts selecting from the cursor, and Y or
+// ENTER copies the selected text, or the character under the cursor, and leaves copy
+// mode. ESC or Q leave without copying. Positions are in the frame rather than the
+// TTY, so that the selection stays put when the page scrolls.
+var copyMode struct {
+	isActive    bool
+	isSelecting bool
+	x, y        int
+	anchorX     int
+	anchorY     int
+}
+
+func startCopyMode() {
+	xScroll, yScroll := CurrentTab.frame.viewport.position()
+	copyMode.isActive = true
+	copyMode.isSelecting = false
+	copyMode.x, copyMode.y = xScroll, yScroll
+	renderCurrentTabWindow()
+}
+
+func stopCopyMode() {
+	copyMode.isActive = false
+	renderCurrentTabWindow()
+}
+
+func handleCopyModeKeyPress(ev *tcell.EventKey) {
+	switch {
+	case ev.Key() == tcell.KeyLeft || ev.Rune() == 'h':
+		moveCopyModeCursor(-1, 0)
+	case ev.Key() == tcell.KeyRight || ev.Rune() == 'l':
+		moveCopyModeCursor(1, 0)
+	case ev.Key() == tcell.KeyUp || ev.Rune() == 'k':
+		moveCopyModeCursor(0, -1)
+	case ev.Key() == tcell.KeyDown || ev.Rune() == 'j':
+		moveCopyModeCursor(0, 1)
+	case ev.Rune() == 'v':
+		copyMode.isSelecting = !copyMode.isSelecting
+		copyMode.anchorX, copyMode.anchorY = copyMode.x, copyMode.y
+	case ev.Key() == tcell.KeyEnter || ev.Rune() == 'y':
+		text := copySelection()
+		copyToClipboard(text)
+		copyMode.isActive = false
+		sendMessageToWebExtension(fmt.Sprintf("/status,Copied %d characters", len([]rune(text))))
+	case ev.Key() == tcell.KeyEscape || ev.Rune() == 'q':
+		copyMode.isActive = false
+	}
+	renderCurrentTabWindow()
+}
+
+// The page scrolls to keep the cursor on screen
+func moveCopyModeCursor(dx, dy int) {
+	f := &CurrentTab.frame
+	copyMode.x = clamp(copyMode.x+dx, 0, f.totalWidth-1)
+	copyMode.y = clamp(copyMode.y+dy, 0, f.domRowCount()-1)
+	_, yScroll := f.viewport.position()
+	_, rows := focusedPaneArea()
+	if copyMode.y < yScroll {
+		scrollFrame(copyMode.y - yScroll)
+	} else if copyMode.y >= yScroll+rows {
+		scrollFrame(copyMode.y - (yScroll + rows - 1))
+	}
+}
+
+func clamp(value, min, max int) int {
+	if value > max {
+		value = max
+	}
+	if value < min {
+		value = min
+	}
+	return value
+}
+
+// The start and end of the selection, in reading order
+func copyModeSelection() (int, int, int, int) {
+	if !copyMode.isSelecting {
+		return copyMode.x, copyMode.y, copyMode.x, copyMode.y
+	}
+	startX, startY, endX, endY := copyMode.anchorX, copyMode.anchorY, copyMode.x, copyMode.y
+	if endY < startY || (endY == startY && endX < startX) {
+		startX, startY, endX, endY = endX, endY, startX, startY
+	}
+	return startX, startY, endX, endY
+}
+
+func copySelection() string {
+	startX, startY, endX, endY := copyModeSelection()
+	return frameTextBetween(&CurrentTab.frame, startX, startY, endX, endY)
+}
+
+// Graphics cells become spaces, and the spaces at the ends of lines are dropped
+func frameTextBetween(f *frame, startX, startY, endX, endY int) string {
+	var lines []string
+	for y := startY; y <= endY; y++ {
+		from, to := 0, f.totalWidth-1
+		if y == startY {
+			from = startX
+		}
+		if y == endY {
+			to = endX
+		}
+		var line []rune
+		for x := from; x <= to; x++ {
+			character := getCell(f, x, y, 0, 0).character
+			if len(character) == 0 || character[0] == '▄' {
+				line = append(line, ' ')
+			} else {
+				line = append(line, character...)
+			}
+		}
+		lines = append(lines, strings.TrimRight(string(line), " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Copies with the OSC 52 escape sequence, which most terminals support, and which works
+// over SSH, as it's the local terminal that does the copying. Tmux needs the sequence
+// wrapping so that it passes it on, and that also needs its `allow-passthrough` option.
+// The terminal's clipboard can't be read back though, so Browsh keeps its own copy to
+// paste with the middle button, along with a history of the rest.
+var lastCopiedText string
+
+func copyToClipboard(text string) {
+	lastCopiedText = text
+	noteCopy(text)
+	os.Stdout.WriteString(clipboardSequence(text, os.Getenv("TMUX") != ""))
+}
+
+func clipboardSequence(text string, isTmux bool) string {
+	sequence := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if isTmux {
+		sequence = "\x1bPtmux;\x1b" + sequence + "\x1b\\"
+	}
+	return sequence
+}
+
+func overlayCopyMode() {
+	if !copyMode.isActive {
+		return
+	}
+	startX, startY, endX, endY := copyModeSelection()
+	xScroll, yScroll := CurrentTab.frame.viewport.position()
+	top, rows := focusedPaneArea()
+	width, _ := screen.Size()
+	for y := clamp(startY, yScroll, yScroll+rows); y <= endY && y < yScroll+rows; y++ {
+		from, to := xScroll, xScroll+width-1
+		if y == startY {
+			from = startX
+		}
+		if y == endY {
+			to = endX
+		}
+		for x := from; x <= to; x++ {
+			reverseCellColour(x-xScroll, top+y-yScroll)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package browsh
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gdamore/tcell"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCopyMode(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Copy mode tests")
+}
+
+// A 4 cell wide frame with a word on each of its 6 rows
+func fillCopyModeFrame(f *frame) {
+	f.totalWidth, f.totalHeight, f.cells = 4, 12, newCellsMap()
+	for y, word := range []string{"one", "two", "six", "ten", "red", "sky"} {
+		for x, character := range word {
+			f.cells.store(y*f.totalWidth+x, cell{character: []rune{character}})
+		}
+	}
+}
+
+var _ = Describe("Copy mode", func() {
+	AfterEach(func() {
+		copyMode.isActive, copyMode.isSelecting = false, false
+		CurrentTab = nil
+		screen = nil
+	})
+
+	It("should order a selection made backwards across rows", func() {
+		copyMode.isSelecting = true
+		copyMode.anchorX, copyMode.anchorY = 1, 3
+		copyMode.x, copyMode.y = 2, 1
+		startX, startY, endX, endY := copyModeSelection()
+		Expect([]int{startX, startY, endX, endY}).To(Equal([]int{2, 1, 1, 3}))
+		copyMode.anchorX, copyMode.anchorY = 2, 1
+		copyMode.x, copyMode.y = 0, 1
+		startX, startY, endX, endY = copyModeSelection()
+		Expect([]int{startX, startY, endX, endY}).To(Equal([]int{0, 1, 2, 1}))
+	})
+
+	It("should copy from the middle of one row to the middle of another", func() {
+		f := &frame{}
+		fillCopyModeFrame(f)
+		Expect(frameTextBetween(f, 1, 0, 1, 2)).To(Equal("ne\ntwo\nsi"))
+	})
+
+	It("should only highlight the selected rows that are on screen", func() {
+		simScreen := tcell.NewSimulationScreen("UTF-8")
+		simScreen.Init()
+		simScreen.SetSize(4, uiHeight+2)
+		screen = simScreen
+		CurrentTab = &tab{}
+		fillCopyModeFrame(&CurrentTab.frame)
+		CurrentTab.frame.viewport.setPosition(0, 2)
+		copyMode.isActive, copyMode.isSelecting = true, true
+		copyMode.anchorX, copyMode.anchorY = 2, 0
+		copyMode.x, copyMode.y = 1, 3
+		overlayCopyMode()
+		isReversed := func(x, y int) bool {
+			_, _, style, _ := simScreen.GetContent(x, y)
+			_, _, attributes := style.Decompose()
+			return attributes&tcell.AttrReverse != 0
+		}
+		for x := 0; x < 4; x++ {
+			Expect(isReversed(x, uiHeight-1)).To(BeFalse(), "UI cell %d", x)
+			Expect(isReversed(x, uiHeight)).To(BeTrue(), "first row cell %d", x)
+			Expect(isReversed(x, uiHeight+1)).To(Equal(x <= 1), "last row cell %d", x)
+		}
+	})
+
+	It("should wrap the clipboard sequence for tmux", func() {
+		Expect(clipboardSequence("hi", false)).To(Equal("\x1b]52;c;aGk=\a"))
+		Expect(clipboardSequence("hi", true)).To(Equal("\x1bPtmux;\x1b\x1b]52;c;aGk=\a\x1b\\"))
+	})
+
+	It("should keep a bounded history of copies, most recent first", func() {
+		defer func() { clipboardHistory.entries = nil }()
+		for i := 0; i < maxClipboardHistoryLength+5; i++ {
+			noteCopy(fmt.Sprintf("copy %d", i))
+		}
+		noteCopy("copy 50")
+		noteCopy("")
+		Expect(clipboardHistory.entries).To(HaveLen(maxClipboardHistoryLength))
+		Expect(clipboardHistory.entries[0]).To(Equal("copy 50"))
+		Expect(clipboardHistory.entries[1]).To(Equal("copy 54"))
+		Expect(clipboardHistory.entries).NotTo(ContainElement("copy 4"))
+	})
+
+	It("should list copies that span lines on one line", func() {
+		Expect(clipboardHistoryLine("one\n  two\tthree")).To(Equal("one two three"))
+	})
+})
@@ -0,0 +1,82 @@
+package browsh
+
+import (
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell"
+)
+
+// Lists, like history, can be much longer than the pane. So only a window of them is
+// shown, that follows the selected item. `pinned` lines, like a search query, stay
+// at the top.
+func overlayListDialog(pinned, items []string, selected int) {
+	_, rows := focusedPaneArea()
+	start, end := dialogWindow(len(items), selected, rows-2-len(pinned))
+	lines := append(append([]string{}, pinned...), items[start:end]...)
+	highlighted := -1
+	if selected >= 0 {
+		highlighted = len(pinned) + selected - start
+	}
+	overlayDialog(lines, highlighted)
+}
+
+// The range of `count` items that fits into `room` lines, with `selected` as near to
+// the middle as it can be
+func dialogWindow(count, selected, room int) (int, int) {
+	if room < 1 {
+		room = 1
+	}
+	if count <= room {
+		return 0, count
+	}
+	start := selected - room/2
+	if start > count-room {
+		start = count - room
+	}
+	if start < 0 {
+		start = 0
+	}
+	return start, start + room
+}
+
+// A box in the middle of the page, with the line at `highlighted` reversed, for
+// dialogs that are navigated with the arrow keys. Use -1 to highlight nothing.
+func overlayDialog(lines []string, highlighted int) {
+	width, _ := screen.Size()
+	top, rows := focusedPaneArea()
+	boxWidth := 0
+	for _, line := range lines {
+		if utf8.RuneCountInString(line) > boxWidth {
+			boxWidth = utf8.RuneCountInString(line)
+		}
+	}
+	boxWidth += 4
+	if boxWidth > width {
+		boxWidth = width
+	}
+	left := (width - boxWidth) / 2
+	top += (rows - len(lines) - 2) / 2
+	if top < 0 {
+		top = 0
+	}
+	style := tcell.StyleDefault.Background(tcell.ColorWhite).Foreground(tcell.ColorBlack)
+	for y := 0; y < len(lines)+2; y++ {
+		for x := 0; x < boxWidth; x++ {
+			screen.SetContent(left+x, top+y, ' ', nil, style)
+		}
+	}
+	for y, line := range lines {
+		lineStyle := style
+		if y == highlighted {
+			lineStyle = style.Reverse(true)
+		}
+		x := left + 2
+		for _, c := range line {
+			if x >= left+boxWidth-1 {
+				break
+			}
+			screen.SetContent(x, top+y+1, c, nil, lineStyle)
+			x++
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package browsh
+
+import (
+	"github.com/gdamore/tcell"
+)
+
+// Terminals without true colour have tcell fit each pixel to its nearest palette
+// colour, which turns the gradients in photos into flat blobs. Dithering instead mixes
+// neighbouring palette colours so that, from a distance, they average out to the
+// original. Ordered dithering nudges each pixel by a fixed pattern, so it's steady
+// between frames. Floyd-Steinberg spreads each pixel's error onto its neighbours,
+// which is more faithful but shimmers more as the page changes.
+var ditherModes = []string{"none", "ordered", "floyd-steinberg"}
+
+// A 4x4 Bayer matrix, the pattern for ordered dithering
+var bayerMatrix = [4][4]int32{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+var (
+	nearestColourCache       = make(map[int32]tcell.Color)
+	nearestColourPaletteSize int
+)
+
+func isValidDitherMode(mode string) bool {
+	for _, valid := range ditherModes {
+		if mode == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// The palette size to dither to, or 0 when there's no need to dither
+func ditherPaletteSize() int {
+	if *ditherMode == "none" || screen == nil {
+		return 0
+	}
+	colours := screen.Colors()
+	if colours > 256 || colours < 8 {
+		return 0
+	}
+	return colours
+}
+
+// Converts a frame's flat list of RGB values into colours, after adjusting them with
+// the picture settings. `left` and `top` are the
+// position of the pixels in the whole frame, so that the ordered pattern lines up
+// between sub frames.
+func pixelColours(data []int32, width, height, left, top, paletteSize int) []tcell.Color {
+	colours := make([]tcell.Color, width*height)
+	data = adjustPicture(data)
+	switch {
+	case paletteSize == 0:
+		for i := range colours {
+			colours[i] = tcell.NewRGBColor(data[i*3], data[i*3+1], data[i*3+2])
+		}
+	case *ditherMode == "ordered":
+		orderedDither(colours, data, width, height, left, top, paletteSize)
+	default:
+		floydSteinbergDither(colours, data, width, height, paletteSize)
+	}
+	return colours
+}
+
+func orderedDither(colours []tcell.Color, data []int32, width, height, left, top, paletteSize int) {
+	// Roughly the gap between neighbouring levels of each channel in the palette
+	spread := int32(128)
+	if paletteSize >= 256 {
+		spread = 40
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			offset := (bayerMatrix[(y+top)%4][(x+left)%4]*2 - 15) * spread / 32
+			colours[i] = nearestPaletteColour(
+				clampChannel(data[i*3]+offset),
+				clampChannel(data[i*3+1]+offset),
+				clampChannel(data[i*3+2]+offset),
+				paletteSize,
+			)
+		}
+	}
+}
+
+func floydSteinbergDither(colours []tcell.Color, data []int32, width, height, paletteSize int) {
+	pixels := make([]int32, len(data))
+	copy(pixels, data)
+	spreadError := func(x, y int, remainder [3]int32, sixteenths int32) {
+		if x < 0 || x >= width || y >= height {
+			return
+		}
+		i := (y*width + x) * 3
+		for channel := 0; channel < 3; channel++ {
+			pixels[i+channel] += remainder[channel] * sixteenths / 16
+		}
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := (y*width + x) * 3
+			r := clampChannel(pixels[i])
+			g := clampChannel(pixels[i+1])
+			b := clampChannel(pixels[i+2])
+			colour := nearestPaletteColour(r, g, b, paletteSize)
+			colours[y*width+x] = colour
+			pr, pg, pb := colour.RGB()
+			remainder := [3]int32{r - pr, g - pg, b - pb}
+			spreadError(x+1, y, remainder, 7)
+			spreadError(x-1, y+1, remainder, 3)
+			spreadError(x, y+1, remainder, 5)
+			spreadError(x+1, y+1, remainder, 1)
+		}
+	}
+}
+
+// Tcell has its own colour fitting, but it converts every colour to CIE Lab, which is
+// too slow to do for every pixel of every frame. So this is a plain RGB distance,
+// weighted for how sensitive eyes are to each channel.
+func nearestPaletteColour(r, g, b int32, paletteSize int) tcell.Color {
+	// There are far fewer colours on a page than possible colours, but a photo can
+	// have a lot, so the cache is only allowed to grow so big.
+	if paletteSize != nearestColourPaletteSize || len(nearestColourCache) > 1<<16 {
+		nearestColourCache = make(map[int32]tcell.Color)
+		nearestColourPaletteSize = paletteSize
+	}
+	key := r<<16 | g<<8 | b
+	if colour, ok := nearestColourCache[key]; ok {
+		return colour
+	}
+	var nearest tcell.Color
+	nearestDistance := int32(-1)
+	for i := 0; i < paletteSize; i++ {
+		pr, pg, pb := tcell.Color(i).RGB()
+		distance := 3*(r-pr)*(r-pr) + 4*(g-pg)*(g-pg) + 2*(b-pb)*(b-pb)
+		if nearestDistance < 0 || distance < nearestDistance {
+			nearest = tcell.Color(i)
+			nearestDistance = distance
+		}
+	}
+	nearestColourCache[key] = nearest
+	return nearest
+}
+
+func clampChannel(value int32) int32 {
+	if value < 0 {
+		return 0
+	}
+	if value > 255 {
+		return 255
+	}
+	return value
+}
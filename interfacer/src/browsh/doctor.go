@@ -0,0 +1,72 @@
+package browsh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"runtime"
+)
+
+// `browsh doctor` checks the things Browsh needs before starting it, so that problems
+// are all reported at once, with some explanation, rather than Browsh quitting at the
+// first one. Flags and the config file are taken into account, as they're checked
+// after being parsed.
+func runDoctor(out io.Writer) int {
+	problems := 0
+	report := func(err error, ok string) {
+		if err != nil {
+			problems++
+			fmt.Fprintln(out, "✘ "+err.Error())
+		} else {
+			fmt.Fprintln(out, "✔ "+ok)
+		}
+	}
+	fmt.Fprintf(out, "Browsh v%s on %s/%s\n", browshVersion, runtime.GOOS, runtime.GOARCH)
+	for _, problem := range validateFlags() {
+		report(fmt.Errorf("%s", problem), "")
+	}
+	configPath, _ := getConfigFilePath()
+	if _, err := os.Stat(configPath); err == nil {
+		fmt.Fprintln(out, "✔ Config file: "+configPath)
+	} else {
+		fmt.Fprintln(out, "- No config file at "+configPath)
+	}
+	if *isUseExistingFirefox {
+		fmt.Fprintln(out, "- Using an existing Firefox, so it isn't checked")
+	} else {
+		binary, err := findFirefoxBinary()
+		report(err, "Firefox: "+binary)
+		if err == nil {
+			version, err := checkFirefoxVersion(binary)
+			report(err, "Firefox version: "+version)
+		}
+	}
+	if listener, err := net.Listen("tcp", ":"+*webSocketPort); err == nil {
+		listener.Close()
+		report(nil, "Websocket port "+*webSocketPort+" is free")
+	} else {
+		report(fmt.Errorf("The websocket port %s is in use, is Browsh already running? "+
+			"Change it with --websocket-port", *webSocketPort), "")
+	}
+	if runtime.GOOS != "windows" {
+		fmt.Fprintf(out, "- TERM=%s COLORTERM=%s\n", os.Getenv("TERM"), os.Getenv("COLORTERM"))
+	}
+	screen, err := setupColourMode()
+	if err == nil {
+		err = screen.Init()
+	}
+	if err == nil {
+		colours := screen.Colors()
+		screen.Fini()
+		report(nil, fmt.Sprintf("Terminal: %d colours", colours))
+	} else {
+		report(fmt.Errorf("The terminal can't be used: %s", err), "")
+	}
+	if problems > 0 {
+		fmt.Fprintf(out, "%d problem(s) found\n", problems)
+		return 1
+	}
+	fmt.Fprintln(out, "Everything looks good")
+	return 0
+}
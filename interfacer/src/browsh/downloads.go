@@ -0,0 +1,129 @@
+package browsh
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/gdamore/tcell"
+)
+
+// Firefox saves downloads to the usual downloads folder without asking, as there'd be
+// no way to answer its dialog. The webextension reports their progress, which is shown
+// on the status line. ALT+J lists this session's downloads, and ENTER copies the
+// selected one's path, as the file is on the machine Browsh runs on, which isn't
+// necessarily the one the terminal is on.
+type download struct {
+	ID       int    `json:"id"`
+	Filename string `json:"filename"`
+	State    string `json:"state"`
+	Received int64  `json:"received"`
+	Total    int64  `json:"total"`
+	Error    string `json:"error"`
+}
+
+var downloads struct {
+	sync.Mutex
+	all []download
+}
+
+var downloadList struct {
+	isVisible bool
+	selected  int
+}
+
+func handleDownload(jsonString string) {
+	var incoming download
+	if err := json.Unmarshal([]byte(jsonString), &incoming); err != nil {
+		logError("Couldn't parse a download: " + err.Error())
+		return
+	}
+	previous, ok := updateDownload(incoming)
+	if !ok || previous.State != incoming.State {
+		switch incoming.State {
+		case "complete":
+			fireDownloadEvent("download_complete", incoming)
+		case "interrupted":
+			fireDownloadEvent("download_failed", incoming)
+		}
+	}
+	if ok && previous.describe() == incoming.describe() {
+		return
+	}
+	sendMessageToWebExtension("/status," + incoming.describe())
+}
+
+// Returns the download as it was before, if it's been seen already
+func updateDownload(incoming download) (download, bool) {
+	downloads.Lock()
+	defer downloads.Unlock()
+	for i, existing := range downloads.all {
+		if existing.ID == incoming.ID {
+			downloads.all[i] = incoming
+			return existing, true
+		}
+	}
+	downloads.all = append(downloads.all, incoming)
+	return download{}, false
+}
+
+func (d download) describe() string {
+	name := filepath.Base(d.Filename)
+	switch d.State {
+	case "complete":
+		return "Downloaded " + d.Filename
+	case "interrupted":
+		return fmt.Sprintf("Downloading %s failed: %s", name, d.Error)
+	}
+	if d.Total > 0 {
+		return fmt.Sprintf("Downloading %s %d%%", name, d.Received*100/d.Total)
+	}
+	return fmt.Sprintf("Downloading %s %s", name, formatBytes(float64(d.Received)))
+}
+
+func openDownloadList() {
+	downloads.Lock()
+	count := len(downloads.all)
+	downloads.Unlock()
+	if count == 0 {
+		sendMessageToWebExtension("/status,Nothing has been downloaded yet")
+		return
+	}
+	downloadList.isVisible = true
+	downloadList.selected = count - 1
+	renderCurrentTabWindow()
+}
+
+func handleDownloadListKeyPress(ev *tcell.EventKey) {
+	downloads.Lock()
+	all := append([]download{}, downloads.all...)
+	downloads.Unlock()
+	count := len(all)
+	switch ev.Key() {
+	case tcell.KeyUp:
+		downloadList.selected = (downloadList.selected - 1 + count) % count
+	case tcell.KeyDown:
+		downloadList.selected = (downloadList.selected + 1) % count
+	case tcell.KeyEnter:
+		downloadList.isVisible = false
+		copyToClipboard(all[downloadList.selected].Filename)
+		sendMessageToWebExtension("/status,Copied " + all[downloadList.selected].Filename)
+	case tcell.KeyEscape:
+		downloadList.isVisible = false
+	}
+	renderCurrentTabWindow()
+}
+
+func overlayDownloadList() {
+	if !downloadList.isVisible {
+		return
+	}
+	downloads.Lock()
+	defer downloads.Unlock()
+	lines := make([]string, len(downloads.all))
+	for i, d := range downloads.all {
+		lines[i] = d.describe()
+	}
+	overlayListDialog(nil, lines, downloadList.selected)
+}
@@ -0,0 +1,36 @@
+package browsh
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDownloads(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Download tests")
+}
+
+var _ = Describe("Downloads", func() {
+	AfterEach(func() {
+		downloads.all = nil
+	})
+
+	It("should describe a download's progress", func() {
+		d := download{Filename: "/home/user/Downloads/browsh.deb", State: "in_progress", Received: 512, Total: 2048}
+		Expect(d.describe()).To(Equal("Downloading browsh.deb 25%"))
+		d.Total = -1
+		Expect(d.describe()).To(Equal("Downloading browsh.deb 512B"))
+		d.State = "complete"
+		Expect(d.describe()).To(Equal("Downloaded /home/user/Downloads/browsh.deb"))
+	})
+
+	It("should keep one entry for each download", func() {
+		handleDownload(`{"id":1,"filename":"a.zip","state":"in_progress","received":1,"total":10}`)
+		handleDownload(`{"id":2,"filename":"b.zip","state":"in_progress","received":1,"total":10}`)
+		handleDownload(`{"id":1,"filename":"a.zip","state":"complete","received":10,"total":10}`)
+		Expect(downloads.all).To(HaveLen(2))
+		Expect(downloads.all[0].State).To(Equal("complete"))
+	})
+})
@@ -0,0 +1,139 @@
+package browsh
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Headless Firefox can't show its file picker, and it wouldn't be usable at TTY
+// resolutions anyway. So when a page's file input is clicked, the webextension asks
+// for the path of a local file instead, which is then sent back to the input.
+const maxUploadSize = 10 * 1024 * 1024
+
+type incomingFileInput struct {
+	ID string `json:"id"`
+}
+
+type outgoingFileInputContent struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Data string `json:"data"`
+}
+
+func handleFileInputRequest(jsonString string) {
+	var incoming incomingFileInput
+	if err := json.Unmarshal([]byte(jsonString), &incoming); err != nil {
+		Shutdown(err)
+	}
+	// A public terminal mustn't let anyone browse, or upload, the host's files
+	if *isKioskMode {
+		marshalled, _ := json.Marshal(incoming)
+		sendMessageToWebExtension("/tab_command,/file_input_cancel," + string(marshalled))
+		sendMessageToWebExtension("/status,Uploading files isn't allowed here")
+		return
+	}
+	startingDir, err := os.Getwd()
+	if err != nil {
+		startingDir = ""
+	} else {
+		startingDir += string(filepath.Separator)
+	}
+	queuePrompt(func() {
+		openPrompt("Upload file: ", startingDir, func(path string) {
+			sendFileToInput(incoming.ID, path)
+		})
+		promptCompleter = completeFilePath
+	})
+}
+
+func sendFileToInput(id, path string) {
+	path = expandHomeDir(strings.TrimSpace(path))
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		sendMessageToWebExtension("/status,Not a file: " + path)
+		return
+	}
+	if info.Size() > maxUploadSize {
+		sendMessageToWebExtension(fmt.Sprintf(
+			"/status,%s is too big to upload, the limit is %dMB", path, maxUploadSize/1024/1024))
+		return
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		sendMessageToWebExtension("/status,Couldn't read " + path)
+		return
+	}
+	content := outgoingFileInputContent{
+		ID:   id,
+		Name: filepath.Base(path),
+		Type: mime.TypeByExtension(filepath.Ext(path)),
+		Data: base64.StdEncoding.EncodeToString(data),
+	}
+	marshalled, _ := json.Marshal(content)
+	sendMessageToWebExtension("/tab_command,/file_input_content," + string(marshalled))
+}
+
+func expandHomeDir(path string) string {
+	if !strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		return path
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+// Like a shell's TAB completion, complete as much of the path as all the matching
+// files have in common, adding a separator when it's a directory. The directory is
+// listed rather than globbed, as names can have characters like `[` and `*` in them.
+func completeFilePath(text string) string {
+	dir, prefix := filepath.Split(expandHomeDir(text))
+	listed := dir
+	if listed == "" {
+		listed = "."
+	}
+	files, err := ioutil.ReadDir(listed)
+	if err != nil {
+		return text
+	}
+	var matches []string
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), prefix) {
+			matches = append(matches, dir+file.Name())
+		}
+	}
+	if len(matches) == 0 {
+		return text
+	}
+	common := matches[0]
+	for _, match := range matches[1:] {
+		for !strings.HasPrefix(match, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	// Names can share the first bytes of different characters
+	for len(common) < len(matches[0]) && !utf8.RuneStart(matches[0][len(common)]) {
+		common = common[:len(common)-1]
+	}
+	if len(matches) == 1 {
+		if info, err := os.Stat(common); err == nil && info.IsDir() {
+			common += string(filepath.Separator)
+		}
+	}
+	if strings.HasPrefix(text, "~"+string(filepath.Separator)) && os.Getenv("HOME") != "" {
+		common = "~" + strings.TrimPrefix(common, os.Getenv("HOME"))
+	}
+	if len(common) < len(text) {
+		return text
+	}
+	return common
+}
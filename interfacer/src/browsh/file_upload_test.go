@@ -0,0 +1,55 @@
+package browsh
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFileUpload(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "File upload tests")
+}
+
+var _ = Describe("Completing file paths", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "browsh-upload")
+		os.Mkdir(filepath.Join(dir, "photos"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "report-2018.pdf"), []byte{}, 0644)
+		ioutil.WriteFile(filepath.Join(dir, "report-2019.pdf"), []byte{}, 0644)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should complete as far as the matches have in common", func() {
+		Expect(completeFilePath(filepath.Join(dir, "re"))).To(Equal(filepath.Join(dir, "report-201")))
+	})
+
+	It("should only complete whole characters", func() {
+		ioutil.WriteFile(filepath.Join(dir, "cv-é.pdf"), []byte{}, 0644)
+		ioutil.WriteFile(filepath.Join(dir, "cv-è.pdf"), []byte{}, 0644)
+		Expect(completeFilePath(filepath.Join(dir, "cv"))).To(Equal(filepath.Join(dir, "cv-")))
+	})
+
+	It("should complete names with glob characters in them", func() {
+		ioutil.WriteFile(filepath.Join(dir, "draft[1]*.txt"), []byte{}, 0644)
+		Expect(completeFilePath(filepath.Join(dir, "draft["))).To(Equal(filepath.Join(dir, "draft[1]*.txt")))
+		Expect(completeFilePath(filepath.Join(dir, "draft[1]"))).To(Equal(filepath.Join(dir, "draft[1]*.txt")))
+	})
+
+	It("should add a separator to a directory", func() {
+		Expect(completeFilePath(filepath.Join(dir, "ph"))).To(Equal(filepath.Join(dir, "photos") + "/"))
+	})
+
+	It("should leave text with no matches alone", func() {
+		Expect(completeFilePath(filepath.Join(dir, "xyz"))).To(Equal(filepath.Join(dir, "xyz")))
+	})
+})
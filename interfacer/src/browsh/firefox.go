@@ -2,6 +2,7 @@ package browsh
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -10,7 +11,9 @@ import (
 	"os/exec"
 	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gdamore/tcell"
@@ -19,8 +22,13 @@ import (
 
 var (
 	marionette     net.Conn
+	marionetteLock sync.Mutex
 	ffCommandCount = 0
-	defaultFFPrefs = map[string]string{
+	// Only set when Browsh started Firefox itself
+	firefoxProcess *exec.Cmd
+	// So that Firefox exiting because it was asked to isn't mistaken for a crash
+	isFirefoxQuitting = false
+	defaultFFPrefs    = map[string]string{
 		"browser.startup.homepage":                "'https://www.google.com'",
 		"startup.homepage_welcome_url":            "'https://www.google.com'",
 		"startup.homepage_welcome_url.additional": "''",
@@ -49,10 +57,26 @@ var (
 		// the privacy info page to be opened on every "web-ext run".
 		// (See #1114 for rationale)
 		"datareporting.policy.firstRunURL": "''",
+
+		// Save downloads to the downloads folder without asking, as the dialog can't
+		// be seen from the TTY
+		"browser.download.useDownloadDir":           "true",
+		"browser.download.folderList":               "1",
+		"browser.download.panel.shown":              "true",
+		"browser.download.manager.showWhenStarting": "false",
+		"browser.helperApps.neverAsk.saveToDisk": "'application/octet-stream,application/zip," +
+			"application/x-tar,application/gzip,application/x-gzip,application/x-bzip2," +
+			"application/x-xz,application/x-7z-compressed,application/vnd.rar," +
+			"application/x-msdownload,application/x-debian-package,application/x-rpm," +
+			"application/epub+zip,application/msword,application/vnd.ms-excel," +
+			"application/vnd.openxmlformats-officedocument.wordprocessingml.document," +
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet," +
+			"text/csv,audio/mpeg,video/mp4,image/png,image/jpeg'",
 	}
 )
 
 func startHeadlessFirefox() {
+	defer recoverAndShutdown()
 	checkIfFirefoxIsAlreadyRunning()
 	Log("Starting Firefox in headless mode")
 	ensureFirefoxBinary()
@@ -69,19 +93,94 @@ func startHeadlessFirefox() {
 		Log("Using default profile at: " + profilePath)
 		args = append(args, "--profile", profilePath)
 	}
-	firefoxProcess := exec.Command(*firefoxBinary, args...)
-	defer firefoxProcess.Process.Kill()
-	stdout, err := firefoxProcess.StdoutPipe()
+	process := exec.Command(*firefoxBinary, args...)
+	if *xDisplay != "" {
+		process.Env = append(os.Environ(), "DISPLAY="+*xDisplay)
+	}
+	stdout, err := process.StdoutPipe()
 	if err != nil {
 		Shutdown(err)
 	}
-	if err := firefoxProcess.Start(); err != nil {
+	if err := process.Start(); err != nil {
 		Shutdown(err)
 	}
+	firefoxProcess = process
+	started := time.Now()
 	in := bufio.NewScanner(stdout)
 	for in.Scan() {
 		Log("FF-CONSOLE: " + in.Text())
 	}
+	superviseFirefoxExit(process.Wait(), time.Since(started))
+}
+
+// Firefox can crash, or be killed by the OOM killer, long into a session. Rather than
+// leaving a dead Browsh behind, start it again. Tabs can't be recovered, so it starts
+// from the startup URL. Firefox that dies soon after starting will only die again.
+func superviseFirefoxExit(err error, ranFor time.Duration) {
+	if isFirefoxQuitting {
+		return
+	}
+	message := "Firefox stopped unexpectedly"
+	if err != nil {
+		message += " (" + err.Error() + ")"
+	}
+	if ranFor < 30*time.Second {
+		Shutdown(errors.New(message + ", too soon after starting to try again"))
+	}
+	logError(message + ", restarting it")
+	if !*IsHTTPServer {
+		restarted := &firefoxRestartEvent{message: message + ", restarting it..."}
+		restarted.SetEventNow()
+		screen.PostEvent(restarted)
+	}
+	marionetteLock.Lock()
+	if marionette != nil {
+		marionette.Close()
+		marionette = nil
+	}
+	marionetteLock.Unlock()
+	startAndSetupFirefox()
+}
+
+// The tabs and the screen belong to the goroutine reading STDIN, so rather than
+// changing them from Firefox's goroutine, the restart is posted to it like a key press
+type firefoxRestartEvent struct {
+	tcell.EventTime
+	message string
+}
+
+// Overlays and the split pane all refer to tabs that died with Firefox, so they go too
+func handleFirefoxRestart(ev *firefoxRestartEvent) {
+	CurrentTab = nil
+	Tabs = make(map[int]*tab)
+	tabsOrder = nil
+	unsplit()
+	tabList.isVisible = false
+	urlList.isVisible = false
+	downloadList.isVisible = false
+	clipboardHistory.isVisible = false
+	copyMode.isActive = false
+	hideQRCode()
+	stopSelectingURL()
+	isCommandMode = false
+	certErrorLock.Lock()
+	currentCertError = nil
+	certErrorLock.Unlock()
+	queuedPrompts = nil
+	closePrompt()
+	_, height := screen.Size()
+	writeString(0, height-1, ev.message, tcell.StyleDefault)
+	screen.Show()
+}
+
+// Firefox is normally asked to quit nicely, but when Browsh is stopped by an error it
+// mustn't be left running, as it would stop the next Browsh from starting.
+func killFirefox() {
+	if firefoxProcess == nil || firefoxProcess.ProcessState != nil {
+		return
+	}
+	isFirefoxQuitting = true
+	firefoxProcess.Process.Kill()
 }
 
 func checkIfFirefoxIsAlreadyRunning() {
@@ -96,37 +195,60 @@ func checkIfFirefoxIsAlreadyRunning() {
 }
 
 func ensureFirefoxBinary() {
-	if *firefoxBinary == "firefox" {
+	binary, err := findFirefoxBinary()
+	if err != nil {
+		Shutdown(err)
+	}
+	*firefoxBinary = binary
+}
+
+func findFirefoxBinary() (string, error) {
+	binary := *firefoxBinary
+	if binary == "firefox" {
 		switch runtime.GOOS {
 		case "windows":
-			*firefoxBinary = `c:\Program Files (x86)\Mozilla Firefox\firefox.exe`
+			binary = `c:\Program Files (x86)\Mozilla Firefox\firefox.exe`
 		case "darwin":
-			*firefoxBinary = "/Applications/Firefox.app/Contents/MacOS/firefox"
+			binary = "/Applications/Firefox.app/Contents/MacOS/firefox"
 		default:
-			*firefoxBinary = Shell("which firefox")
+			path, err := exec.LookPath("firefox")
+			if err != nil {
+				return "", errors.New("Firefox binary not found in $PATH, set --firefox")
+			}
+			binary = path
 		}
 	}
-	if _, err := os.Stat(*firefoxBinary); os.IsNotExist(err) {
-		if runtime.GOOS == "windows" {
-			*firefoxBinary = `c:\Program Files\Mozilla Firefox\firefox.exe`
-			if _, err := os.Stat(*firefoxBinary); os.IsNotExist(err) {
-				Shutdown(errors.New(`Firefox binary not found in: c:\Program Files (x86)\Mozilla Firefox\firefox.exe or ` + *firefoxBinary))
+	if _, err := os.Stat(binary); os.IsNotExist(err) {
+		if runtime.GOOS == "windows" && *firefoxBinary == "firefox" {
+			binary = `c:\Program Files\Mozilla Firefox\firefox.exe`
+			if _, err := os.Stat(binary); os.IsNotExist(err) {
+				return "", errors.New(`Firefox binary not found in: c:\Program Files (x86)\Mozilla Firefox\firefox.exe or ` + binary)
 			}
 		} else {
-			Shutdown(errors.New("Firefox binary not found: " + *firefoxBinary))
+			return "", errors.New("Firefox binary not found: " + binary)
 		}
 	}
+	return binary, nil
 }
 
 func ensureFirefoxVersion() {
-	output := Shell(*firefoxBinary + " --version")
-	pieces := strings.Split(output, " ")
+	if _, err := checkFirefoxVersion(*firefoxBinary); err != nil {
+		Shutdown(err)
+	}
+}
+
+func checkFirefoxVersion(binary string) (string, error) {
+	output, err := exec.Command(binary, "--version").CombinedOutput()
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Browsh tried to run `%s --version` but failed with: %s", binary, output))
+	}
+	pieces := strings.Split(strings.TrimSpace(string(output)), " ")
 	version := pieces[len(pieces)-1]
 	if versionOrdinal(version) < versionOrdinal("57") {
-		message := "Installed Firefox version " + version + " is too old. " +
-			"Firefox 57 or newer is needed."
-		Shutdown(errors.New(message))
+		return version, errors.New("Installed Firefox version " + version + " is too old. " +
+			"Firefox 57 or newer is needed.")
 	}
+	return version, nil
 }
 
 // Taken from https://stackoverflow.com/a/18411978/575773
@@ -163,6 +285,7 @@ func versionOrdinal(version string) string {
 // because I haven't been able to recreate the way `web-ext` injects an unsigned
 // extension.
 func startWERFirefox() {
+	defer recoverAndShutdown()
 	Log("Attempting to start headless Firefox with `web-ext`")
 	var rootDir = Shell("git rev-parse --show-toplevel")
 	args := []string{
@@ -249,6 +372,8 @@ func installWebextension() {
 // Set a Firefox preference as you would in `about:config`
 // `value` needs to be supplied with quotes if it's to be used as a JS string
 func setFFPreference(key string, value string) {
+	marionetteLock.Lock()
+	defer marionetteLock.Unlock()
 	sendFirefoxCommand("setContext", map[string]interface{}{"value": "chrome"})
 	script := fmt.Sprintf(`
 		Components.utils.import("resource://gre/modules/Preferences.jsm");
@@ -259,25 +384,82 @@ func setFFPreference(key string, value string) {
 	sendFirefoxCommand("setContext", map[string]interface{}{"value": "content"})
 }
 
-// Consume output from Marionette, we don't do anything with it. It"s just
-// useful to have it in the logs.
-func readMarionette() {
+// Consume output from Marionette. Mostly we don't do anything with it, it's just
+// useful to have it in the logs. Each message is prefixed with its length, so keep
+// reading until all of it has arrived.
+func readMarionette() []byte {
 	buffer := make([]byte, 4096)
 	count, err := marionette.Read(buffer)
 	if err != nil {
 		Shutdown(err)
 	}
-	Log("FF-MRNT: " + string(buffer[:count]))
+	message := append([]byte{}, buffer[:count]...)
+	separator := bytes.IndexByte(message, ':')
+	if separator > 0 {
+		if length, err := strconv.Atoi(string(message[:separator])); err == nil {
+			for len(message)-separator-1 < length {
+				count, err = marionette.Read(buffer)
+				if err != nil {
+					Shutdown(err)
+				}
+				message = append(message, buffer[:count]...)
+			}
+			message = message[separator+1:]
+		}
+	}
+	Log("FF-MRNT: " + string(message))
+	return message
 }
 
-func sendFirefoxCommand(command string, args map[string]interface{}) {
+func sendFirefoxCommand(command string, args map[string]interface{}) []byte {
 	Log("Sending `" + command + "` to Firefox Marionette")
 	fullCommand := []interface{}{0, ffCommandCount, command, args}
 	marshalled, _ := json.Marshal(fullCommand)
 	message := fmt.Sprintf("%d:%s", len(marshalled), marshalled)
 	fmt.Fprintf(marionette, message)
 	ffCommandCount++
-	readMarionette()
+	return readMarionette()
+}
+
+// Run a script with the browser's own privileges, for the things that the
+// webextension APIs don't allow. The script is asynchronous, it returns its result by
+// calling the last of its `arguments`. Unlike the rest of Marionette's use, this can
+// happen whilst browsing, hence the lock.
+func runFirefoxChromeScript(script string, args []interface{}) (json.RawMessage, error) {
+	if marionette == nil {
+		return nil, errors.New("Browsh isn't connected to Firefox's Marionette")
+	}
+	marionetteLock.Lock()
+	defer marionetteLock.Unlock()
+	sendFirefoxCommand("setContext", map[string]interface{}{"value": "chrome"})
+	response := sendFirefoxCommand("executeAsyncScript", map[string]interface{}{
+		"script": script,
+		"args":   args,
+	})
+	sendFirefoxCommand("setContext", map[string]interface{}{"value": "content"})
+	return parseMarionetteResponse(response)
+}
+
+// Responses look like `[1, <command ID>, <error>, {"value": <result>}]`
+func parseMarionetteResponse(response []byte) (json.RawMessage, error) {
+	var parts []json.RawMessage
+	if err := json.Unmarshal(response, &parts); err != nil || len(parts) != 4 {
+		return nil, fmt.Errorf("Unexpected response from Marionette: %s", response)
+	}
+	if string(parts[2]) != "null" {
+		var failure struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(parts[2], &failure)
+		return nil, errors.New("Marionette script failed: " + failure.Message)
+	}
+	var result struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(parts[3], &result); err != nil {
+		return nil, err
+	}
+	return result.Value, nil
 }
 
 func setDefaultPreferences() {
@@ -286,13 +468,16 @@ func setDefaultPreferences() {
 	}
 }
 
+// How long before the time limit is reached that the user gets warned
+const timeLimitWarningLength = 10
+
 func beginTimeLimit() {
-	warningLength := 10
-	warningLimit := time.Duration(*timeLimit - warningLength)
+	defer recoverAndShutdown()
+	warningLimit := time.Duration(*timeLimit - timeLimitWarningLength)
 	time.Sleep(warningLimit * time.Second)
-	message := fmt.Sprintf("Browsh will close in %d seconds...", warningLength)
+	message := fmt.Sprintf("Browsh will close in %d seconds...", timeLimitWarningLength)
 	sendMessageToWebExtension("/status," + message)
-	time.Sleep(time.Duration(warningLength) * time.Second)
+	time.Sleep(time.Duration(timeLimitWarningLength) * time.Second)
 	quitBrowsh()
 }
 
@@ -300,10 +485,14 @@ func beginTimeLimit() {
 // tests, because it uses the officially signed webextension, of which there can be only one.
 // We can't bump the version and create a new signed webextension for every commit.
 func setupFirefox() {
-	go startHeadlessFirefox()
 	if *timeLimit > 0 {
 		go beginTimeLimit()
 	}
+	startAndSetupFirefox()
+}
+
+func startAndSetupFirefox() {
+	go startHeadlessFirefox()
 	firefoxMarionette()
 	setDefaultPreferences()
 	installWebextension()
@@ -324,5 +513,10 @@ func startFirefox() {
 }
 
 func quitFirefox() {
+	isFirefoxQuitting = true
+	if marionette == nil {
+		Log("Firefox hasn't connected to Marionette, so it can't be asked to quit")
+		return
+	}
 	sendFirefoxCommand("quitApplication", map[string]interface{}{})
 }
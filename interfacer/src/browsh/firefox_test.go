@@ -0,0 +1,70 @@
+package browsh
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFirefox(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Firefox tests")
+}
+
+var _ = Describe("Marionette responses", func() {
+	It("should return a script's result", func() {
+		result, err := parseMarionetteResponse([]byte(`[1,4,null,{"value":{"issuer":"CN=Test"}}]`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(result)).To(Equal(`{"issuer":"CN=Test"}`))
+	})
+
+	It("should return a script's error", func() {
+		_, err := parseMarionetteResponse(
+			[]byte(`[1,4,{"error":"javascript error","message":"cert is undefined"},null]`))
+		Expect(err).To(MatchError(ContainSubstring("cert is undefined")))
+	})
+
+	It("should reject anything else", func() {
+		_, err := parseMarionetteResponse([]byte(`{"applicationType":"gecko"}`))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Restarting Firefox", func() {
+	BeforeEach(func() {
+		simScreen := tcell.NewSimulationScreen("UTF-8")
+		simScreen.Init()
+		simScreen.SetSize(40, 10)
+		screen = simScreen
+	})
+
+	AfterEach(func() {
+		screen = nil
+	})
+
+	It("should forget the split pane and anything open over the dead tabs", func() {
+		newTab(1)
+		newTab(2)
+		CurrentTab = Tabs[1]
+		split()
+		tabList.isVisible = true
+		currentCertError = &certError{URL: "https://expired.example.com/"}
+		queuedPrompts = []func(){func() {}}
+		qrCodeOverlay = qrCode{{true}}
+		isSelectingURL = true
+		isCommandMode = true
+		handleFirefoxRestart(&firefoxRestartEvent{message: "Firefox crashed"})
+		Expect(isSplit).To(BeFalse())
+		Expect(splitTab).To(BeNil())
+		Expect(tabList.isVisible).To(BeFalse())
+		Expect(isCertErrorShown()).To(BeFalse())
+		Expect(queuedPrompts).To(BeEmpty())
+		Expect(qrCodeOverlay).To(BeNil())
+		Expect(isSelectingURL).To(BeFalse())
+		Expect(isCommandMode).To(BeFalse())
+		Expect(Tabs).To(BeEmpty())
+		Expect(tabsOrder).To(BeEmpty())
+	})
+})
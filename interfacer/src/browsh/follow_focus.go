@@ -0,0 +1,38 @@
+package browsh
+
+// Whilst following focus the TTY keeps whatever element has focus in the page, eg; as
+// TAB moves through a form or a link is followed by its text, in the middle of the
+// focused pane. Otherwise it's easy to lose track of where the focus has gone on a
+// page taller than the TTY.
+var isFollowingFocus = false
+
+func toggleFollowFocus() {
+	if !isFollowingFocus && !requireWebextFeature("follow_focus") {
+		return
+	}
+	isFollowingFocus = !isFollowingFocus
+	if isFollowingFocus {
+		sendMessageToWebExtension("/status,Following focus")
+	} else {
+		sendMessageToWebExtension("/status,Stopped following focus")
+	}
+}
+
+func handleFocus(top, bottom string) {
+	if !isFollowingFocus || CurrentTab == nil {
+		return
+	}
+	_, yScroll := CurrentTab.frame.viewport.position()
+	_, height := focusedPaneArea()
+	scrollFrame(focusScrollMagnitude(toInt(top), toInt(bottom), yScroll, height))
+}
+
+// Centres the element's rows in a pane of the given height. An element taller than
+// the pane has its top kept in view instead.
+func focusScrollMagnitude(top, bottom, yScroll, height int) int {
+	middle := (top + bottom) / 2
+	if bottom-top >= height {
+		middle = top + height/2
+	}
+	return middle - height/2 - yScroll
+}
@@ -24,8 +24,7 @@ type frame struct {
 	totalHeight int
 	// The current position of the scroll in the TTY. Should be synced with the real
 	// browser.
-	xScroll int
-	yScroll int
+	viewport viewport
 	// Usually we want to just overlay new data. But if the DOM changes then all bets are off
 	// and we need to start from scratch again. It's just too unpredictable how data for a DOM
 	// of a different size and shape will interact with data from another DOM.
@@ -38,6 +37,9 @@ type frame struct {
 	cells *threadSafeCellsMap
 	// Input boxes, like for entering passwords, sending emails etc
 	inputBoxes map[string]*inputBox
+	// Whether the last frame from the browser changed any cells, only tracked when
+	// measuring latency.
+	isChanged bool
 }
 
 type jsonFrameBase struct {
@@ -71,7 +73,9 @@ func (f *frame) subRowCount() int {
 	return f.subHeight / 2
 }
 
-func parseJSONFrameText(jsonString string) {
+// Returns the ID of the tab the frame was for, or -1 if it was dropped
+func parseJSONFrameText(jsonString string) int {
+	countStat(&stats.FramesReceived, 1)
 	var incoming incomingFrameText
 	jsonBytes := []byte(jsonString)
 	if err := json.Unmarshal(jsonBytes, &incoming); err != nil {
@@ -79,21 +83,27 @@ func parseJSONFrameText(jsonString string) {
 	}
 	if !isTabPresent(incoming.Meta.TabID) {
 		Log(fmt.Sprintf("Not building frame for non-existent tab ID: %d", incoming.Meta.TabID))
-		return
+		countStat(&stats.FramesDropped, 1)
+		return -1
 	}
 	Tabs[incoming.Meta.TabID].frame.buildFrameText(incoming)
+	return incoming.Meta.TabID
 }
 
 func (f *frame) buildFrameText(incoming incomingFrameText) {
-	f.setup(incoming.Meta)
+	f.isChanged = false
 	if !f.isIncomingFrameTextValid(incoming) {
+		countStat(&stats.FramesDropped, 1)
 		return
 	}
+	f.setup(incoming.Meta)
 	f.updateInputBoxes(incoming)
 	f.populateFrameText(incoming)
 }
 
-func parseJSONFramePixels(jsonString string) {
+// Returns the ID of the tab the frame was for, or -1 if it was dropped
+func parseJSONFramePixels(jsonString string) int {
+	countStat(&stats.FramesReceived, 1)
 	var incoming incomingFramePixels
 	jsonBytes := []byte(jsonString)
 	if err := json.Unmarshal(jsonBytes, &incoming); err != nil {
@@ -101,19 +111,24 @@ func parseJSONFramePixels(jsonString string) {
 	}
 	if !isTabPresent(incoming.Meta.TabID) {
 		Log(fmt.Sprintf("Not building frame for non-existent tab ID: %d", incoming.Meta.TabID))
-		return
+		countStat(&stats.FramesDropped, 1)
+		return -1
 	}
 	if len(Tabs[incoming.Meta.TabID].frame.text) == 0 {
-		return
+		countStat(&stats.FramesDropped, 1)
+		return -1
 	}
 	Tabs[incoming.Meta.TabID].frame.buildFramePixels(incoming)
+	return incoming.Meta.TabID
 }
 
 func (f *frame) buildFramePixels(incoming incomingFramePixels) {
-	f.setup(incoming.Meta)
+	f.isChanged = false
 	if !f.isIncomingFramePixelsValid(incoming) {
+		countStat(&stats.FramesDropped, 1)
 		return
 	}
+	f.setup(incoming.Meta)
 	f.populateFramePixels(incoming)
 }
 
@@ -142,6 +157,34 @@ func (f *frame) isIncomingFrameTextValid(incoming incomingFrameText) bool {
 		Log("Not parsing zero-size text frame")
 		return false
 	}
+	if !isFrameMetaValid(incoming.Meta) {
+		return false
+	}
+	cellCount := incoming.Meta.SubWidth * (incoming.Meta.SubHeight / 2)
+	if len(incoming.Text) != cellCount || len(incoming.Colours) != cellCount*3 {
+		Log(fmt.Sprintf(
+			"Not parsing text frame with %d characters and %d colours for %d cells",
+			len(incoming.Text), len(incoming.Colours), cellCount))
+		return false
+	}
+	return true
+}
+
+// Frames are built by indexing directly into the flat arrays sent by the webextension,
+// so before doing that we need to be sure the arrays are the size the meta data claims
+// they are. Otherwise a single bad frame would crash the whole TTY.
+func isFrameMetaValid(meta jsonFrameBase) bool {
+	if meta.SubWidth <= 0 || meta.SubHeight <= 0 || meta.SubHeight%2 != 0 {
+		Log(fmt.Sprintf(
+			"Not parsing frame with invalid sub dimensions: %dx%d", meta.SubWidth, meta.SubHeight))
+		return false
+	}
+	if meta.SubLeft < 0 || meta.SubTop < 0 || meta.TotalWidth <= 0 || meta.TotalHeight <= 0 {
+		Log(fmt.Sprintf(
+			"Not parsing frame with invalid position: %d,%d in %dx%d",
+			meta.SubLeft, meta.SubTop, meta.TotalWidth, meta.TotalHeight))
+		return false
+	}
 	return true
 }
 
@@ -180,7 +223,7 @@ func (f *frame) populateFrameText(incoming incomingFrameText) {
 			cellIndex = f.getCellIndexFromSubCoords(x, y*2)
 			frameIndex = (y * f.subWidth) + x
 			colourIndex = frameIndex * 3
-			f.textColours[cellIndex] = tcell.NewRGBColor(
+			f.textColours[cellIndex] = adjustedRGBColour(
 				incoming.Colours[colourIndex+0],
 				incoming.Colours[colourIndex+1],
 				incoming.Colours[colourIndex+2],
@@ -192,31 +235,18 @@ func (f *frame) populateFrameText(incoming incomingFrameText) {
 }
 
 func (f *frame) populateFramePixels(incoming incomingFramePixels) {
-	var cellIndex, frameIndexFg, frameIndexBg, pixelIndexFg, pixelIndexBg int
+	var cellIndex, frameIndexFg, frameIndexBg int
 	if f.isDOMSizeChanged || f.pixels == nil {
 		f.pixels = make(map[int][2]tcell.Color, f.totalHeight*f.totalWidth)
 	}
-	data := incoming.Colours
+	colours := pixelColours(
+		incoming.Colours, f.subWidth, f.subHeight, f.subLeft, f.subTop, ditherPaletteSize())
 	for y := 0; y < f.subHeight; y += 2 {
 		for x := 0; x < f.subWidth; x++ {
 			cellIndex = f.getCellIndexFromSubCoords(x, y)
 			frameIndexBg = (y * f.subWidth) + x
 			frameIndexFg = ((y + 1) * f.subWidth) + x
-			pixelIndexBg = frameIndexBg * 3
-			pixelIndexFg = frameIndexFg * 3
-			pixels := [2]tcell.Color{
-				tcell.NewRGBColor(
-					data[pixelIndexBg+0],
-					data[pixelIndexBg+1],
-					data[pixelIndexBg+2],
-				),
-				tcell.NewRGBColor(
-					data[pixelIndexFg+0],
-					data[pixelIndexFg+1],
-					data[pixelIndexFg+2],
-				),
-			}
-			f.pixels[cellIndex] = pixels
+			f.pixels[cellIndex] = [2]tcell.Color{colours[frameIndexBg], colours[frameIndexFg]}
 			f.buildCell(f.subLeft+x, (f.subTop+y)/2)
 		}
 	}
@@ -227,6 +257,16 @@ func (f *frame) isIncomingFramePixelsValid(incoming incomingFramePixels) bool {
 		Log("Not parsing zero-size text frame")
 		return false
 	}
+	if !isFrameMetaValid(incoming.Meta) {
+		return false
+	}
+	pixelCount := incoming.Meta.SubWidth * incoming.Meta.SubHeight
+	if len(incoming.Colours) != pixelCount*3 {
+		Log(fmt.Sprintf(
+			"Not parsing pixel frame with %d colours for %d pixels",
+			len(incoming.Colours), pixelCount))
+		return false
+	}
 	return true
 }
 
@@ -281,6 +321,10 @@ func (f *frame) addCell(index int, fgColour, bgColour tcell.Color, character []r
 		bgColour:  bgColour,
 		character: character,
 	}
+	if *isLatencyMeasured && !f.isChanged {
+		oldCell, ok := f.cells.load(index)
+		f.isChanged = !ok || !isSameCell(oldCell, newCell)
+	}
 	f.cells.store(index, newCell)
 }
 
@@ -293,14 +337,14 @@ func (f *frame) getCellIndexFromSubCoords(x, y int) int {
 	return (yInAbsoluteFrameTTY * f.totalWidth) + (x + f.subLeft)
 }
 
-func (f *frame) limitScroll(height int) {
-	maxYScroll := f.domRowCount() - height
-	if f.yScroll > maxYScroll {
-		f.yScroll = maxYScroll
-	}
-	if f.yScroll < 0 {
-		f.yScroll = 0
-	}
+// Scroll vertically by the given number of TTY rows, without going past the end of the
+// DOM for a TTY window of the given height.
+func (f *frame) scrollBy(yMagnitude, height int) (int, int) {
+	return f.viewport.scrollBy(0, yMagnitude, f.domRowCount()-height)
+}
+
+func (f *frame) panBy(xMagnitude, yMagnitude, width, height int) (int, int) {
+	return f.viewport.panBy(xMagnitude, yMagnitude, f.totalWidth-width, f.domRowCount()-height)
 }
 
 func (f *frame) maybeFocusInputBox(x, y int) {
@@ -107,6 +107,59 @@ var _ = Describe("Frame struct", func() {
 		})
 	})
 
+	Describe("Malformed frames", func() {
+		It("should not build a text frame with fewer characters than cells", func() {
+			parseJSONFrameText(`{
+				"meta": {
+					"id": 1,
+					"sub_left": 0,
+					"sub_top": 0,
+					"sub_width": 2,
+					"sub_height": 4,
+					"total_width": 2,
+					"total_height": 8
+				},
+				"text": ["A", "b", "c"],
+				"colours": [77, 77, 77, 101, 101, 101, 102, 102, 102]
+			}`)
+			Expect(Tabs[1].frame.cells).To(BeNil())
+		})
+
+		It("should not build a pixel frame with missing colours", func() {
+			Tabs[1].frame.text = map[int][]rune{0: []rune("A")}
+			parseJSONFramePixels(`{
+				"meta": {
+					"id": 1,
+					"sub_left": 0,
+					"sub_top": 0,
+					"sub_width": 2,
+					"sub_height": 4,
+					"total_width": 2,
+					"total_height": 8
+				},
+				"colours": [254, 254, 254, 111, 111, 111]
+			}`)
+			Expect(Tabs[1].frame.cells).To(BeNil())
+		})
+
+		It("should not build a frame with an odd pixel height", func() {
+			parseJSONFrameText(`{
+				"meta": {
+					"id": 1,
+					"sub_left": 0,
+					"sub_top": 0,
+					"sub_width": 1,
+					"sub_height": 3,
+					"total_width": 1,
+					"total_height": 8
+				},
+				"text": ["A"],
+				"colours": [77, 77, 77]
+			}`)
+			Expect(Tabs[1].frame.cells).To(BeNil())
+		})
+	})
+
 	Describe("With Offset", func() {
 		var subFrameJSONText = `{
 			"meta": {
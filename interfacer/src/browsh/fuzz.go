@@ -0,0 +1,70 @@
+// +build gofuzz
+
+package browsh
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Fuzz is the entrypoint for go-fuzz (https://github.com/dvyukov/go-fuzz). It feeds
+// arbitrary messages through the same parsing that frames from the webextension go
+// through, so that malformed frames can't crash the TTY. Build it with
+// `go-fuzz-build browsh/interfacer/src/browsh` and then run it with
+// `go-fuzz -bin=./browsh-fuzz.zip -workdir=fuzz`.
+func Fuzz(data []byte) int {
+	parts := strings.SplitN(string(data), ",", 2)
+	if len(parts) != 2 {
+		return 0
+	}
+	switch parts[0] {
+	case "/frame_text":
+		return fuzzFrameText([]byte(parts[1]))
+	case "/frame_pixels":
+		return fuzzFramePixels([]byte(parts[1]))
+	}
+	return 0
+}
+
+func fuzzFrameText(jsonBytes []byte) int {
+	var incoming incomingFrameText
+	if err := json.Unmarshal(jsonBytes, &incoming); err != nil {
+		return 0
+	}
+	f := &frame{}
+	f.buildFrameText(incoming)
+	if f.cells == nil {
+		return 0
+	}
+	checkFuzzedFrameInvariants(f)
+	return 1
+}
+
+func fuzzFramePixels(jsonBytes []byte) int {
+	var incoming incomingFramePixels
+	if err := json.Unmarshal(jsonBytes, &incoming); err != nil {
+		return 0
+	}
+	f := &frame{}
+	f.buildFramePixels(incoming)
+	if f.cells == nil {
+		return 0
+	}
+	checkFuzzedFrameInvariants(f)
+	return 1
+}
+
+// A single sub frame can never produce more cells than it has TTY cells, and none of
+// those cells can be placed before the start of the frame.
+func checkFuzzedFrameInvariants(f *frame) {
+	maxCells := f.subWidth * f.subRowCount()
+	if count := len(f.cells.internal); count > maxCells {
+		panic(fmt.Sprintf("Built %d cells from a sub frame of only %d", count, maxCells))
+	}
+	for index := range f.cells.internal {
+		if index < 0 {
+			panic(fmt.Sprintf("Built a cell at negative index %d", index))
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package browsh
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-errors/errors"
+)
+
+// The TTY and the webextension are usually released together, but with
+// --use-existing-ff, or an old copy of the extension installed in a profile, they can
+// drift apart. So when the webextension connects it says hello, with its protocol
+// version and the features it supports, and the TTY replies in kind. Features that
+// the other side doesn't know about are then refused with a helpful message, rather
+// than silently doing nothing. Bump the protocol version for changes that aren't just
+// new features.
+const protocolVersion = 1
+
+// The features in the TTY that need the webextension's help
+var ttyFeatures = []string{
+	"allowed_domains",
+	"auth",
+	"cert_errors",
+	"file_input",
+	"find",
+	"follow_focus",
+	"follow_link",
+	"frame_rate",
+	"idle_pause",
+	"kiosk",
+	"media",
+	"read_aloud",
+	"reload",
+	"screenshot",
+	"user_agent",
+	"zoom",
+}
+
+type helloMessage struct {
+	Protocol int      `json:"protocol"`
+	Version  string   `json:"version,omitempty"`
+	Features []string `json:"features"`
+}
+
+type webextensionHello struct {
+	sync.RWMutex
+	isReceived bool
+	hello      helloMessage
+}
+
+var webextHello = &webextensionHello{}
+
+func handleHello(jsonString string) {
+	var incoming helloMessage
+	if err := json.Unmarshal([]byte(jsonString), &incoming); err != nil {
+		Shutdown(err)
+	}
+	webextHello.Lock()
+	webextHello.isReceived = true
+	webextHello.hello = incoming
+	webextHello.Unlock()
+	logInfo(fmt.Sprintf("Webextension %s speaks protocol %d with features: %s",
+		incoming.Version, incoming.Protocol, strings.Join(incoming.Features, ", ")))
+	reply, _ := json.Marshal(helloMessage{Protocol: protocolVersion, Features: ttyFeatures})
+	sendMessageToWebExtension("/hello," + string(reply))
+	if incoming.Protocol != protocolVersion {
+		sendMessageToWebExtension(fmt.Sprintf(
+			"/status,The Browsh webextension speaks protocol %d, but this Browsh speaks %d. "+
+				"Some things may not work, try updating both.", incoming.Protocol, protocolVersion))
+	}
+	if isWebextFeatureSupported("frame_rate") {
+		sendMessageToWebExtension(fmt.Sprintf("/frame_rate,%g,%g,%d,%g", *maxFPS, *idleFPS, *pauseAfter, *videoFPS))
+	}
+	if *pauseAfter > 0 && !isWebextFeatureSupported("idle_pause") {
+		Log("The webextension is too old to pause, so --pause-after won't do anything")
+	}
+	if *allowedDomains != "" {
+		if !isWebextFeatureSupported("allowed_domains") {
+			Shutdown(errors.New("--allowed-domains can't be enforced by this version of the webextension"))
+		}
+		sendMessageToWebExtension("/allowed_domains," + *allowedDomains)
+	}
+	if *isKioskMode {
+		if !isWebextFeatureSupported("kiosk") {
+			Shutdown(errors.New("--kiosk can't refuse downloads with this version of the webextension"))
+		}
+		sendMessageToWebExtension("/kiosk_mode")
+	}
+}
+
+// Webextensions from before the handshake don't say hello at all. Usually that's
+// just a matter of features not working, but an allowlist that isn't enforced, or a
+// kiosk that lets anyone download files to the host, is a security problem, so quit.
+func expectHello() {
+	defer recoverAndShutdown()
+	if *allowedDomains == "" && !*isKioskMode {
+		return
+	}
+	time.Sleep(5 * time.Second)
+	webextHello.RLock()
+	isReceived := webextHello.isReceived
+	webextHello.RUnlock()
+	if !isReceived && *allowedDomains != "" {
+		Shutdown(errors.New("--allowed-domains can't be enforced by this version of the webextension"))
+	}
+	if !isReceived {
+		Shutdown(errors.New("--kiosk can't refuse downloads with this version of the webextension"))
+	}
+}
+
+func isWebextFeatureSupported(feature string) bool {
+	webextHello.RLock()
+	defer webextHello.RUnlock()
+	for _, supported := range webextHello.hello.Features {
+		if supported == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// For features that the user has asked for, so that they're told why nothing happens
+func requireWebextFeature(feature string) bool {
+	if isWebextFeatureSupported(feature) {
+		return true
+	}
+	sendMessageToWebExtension("/status,The Browsh webextension is too old for this, try updating it")
+	return false
+}
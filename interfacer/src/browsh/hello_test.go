@@ -0,0 +1,29 @@
+package browsh
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestHello(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Hello tests")
+}
+
+var _ = Describe("The webextension's hello", func() {
+	AfterEach(func() {
+		webextHello = &webextensionHello{}
+	})
+
+	It("should know nothing is supported before hello", func() {
+		Expect(isWebextFeatureSupported("media")).To(BeFalse())
+	})
+
+	It("should remember the webextension's features", func() {
+		handleHello(`{"protocol":1,"version":"1.4.0","features":["media","zoom"]}`)
+		Expect(isWebextFeatureSupported("media")).To(BeTrue())
+		Expect(isWebextFeatureSupported("read_aloud")).To(BeFalse())
+	})
+})
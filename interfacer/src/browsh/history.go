@@ -0,0 +1,43 @@
+package browsh
+
+import (
+	"strings"
+	"sync"
+)
+
+// The pages visited in this session, most recent first, each only once. ALT+Y lists
+// them. CTRL+H would be the usual key, but terminals send it for BACKSPACE.
+const maxHistoryLength = 500
+
+var history struct {
+	sync.Mutex
+	pages []pageLink
+}
+
+func noteVisit(title, url string) {
+	if url == "" || strings.HasPrefix(url, "about:") {
+		return
+	}
+	history.Lock()
+	defer history.Unlock()
+	history.pages = append([]pageLink{{Title: title, URL: url}}, withoutPageLink(history.pages, url)...)
+	if len(history.pages) > maxHistoryLength {
+		history.pages = history.pages[:maxHistoryLength]
+	}
+}
+
+func openHistoryList() {
+	history.Lock()
+	pages := append([]pageLink{}, history.pages...)
+	history.Unlock()
+	if len(pages) == 0 {
+		sendMessageToWebExtension("/status,No pages visited yet")
+		return
+	}
+	openURLList("History: ", pages, func(removed pageLink) ([]pageLink, error) {
+		history.Lock()
+		defer history.Unlock()
+		history.pages = withoutPageLink(history.pages, removed.URL)
+		return append([]pageLink{}, history.pages...), nil
+	})
+}
@@ -0,0 +1,78 @@
+package browsh
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// When a site asks for HTTP authentication Firefox would normally show its own login
+// dialog, which can't be used from the TTY. Instead the webextension holds on to the
+// request and the credentials are asked for here.
+type incomingAuthRequest struct {
+	ID      string `json:"id"`
+	Host    string `json:"host"`
+	Realm   string `json:"realm"`
+	IsProxy bool   `json:"is_proxy"`
+}
+
+type outgoingAuthCredentials struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Cancel   bool   `json:"cancel"`
+}
+
+// A page can make several requests that all need authenticating, so they're asked
+// about one at a time.
+var pendingAuthRequests []incomingAuthRequest
+
+func handleAuthRequest(jsonString string) {
+	var incoming incomingAuthRequest
+	if err := json.Unmarshal([]byte(jsonString), &incoming); err != nil {
+		Shutdown(err)
+	}
+	pendingAuthRequests = append(pendingAuthRequests, incoming)
+	if len(pendingAuthRequests) == 1 {
+		promptForAuth(incoming)
+	}
+}
+
+func authPromptLabel(request incomingAuthRequest) string {
+	what := request.Host
+	if request.IsProxy {
+		what = "proxy " + what
+	}
+	if request.Realm != "" {
+		what = fmt.Sprintf("%s (%s)", what, request.Realm)
+	}
+	return "Log in to " + what + ". Username: "
+}
+
+func promptForAuth(request incomingAuthRequest) {
+	queuePrompt(func() {
+		openPrompt(authPromptLabel(request), "", func(username string) {
+			openPasswordPrompt("Password: ", func(password string) {
+				finishAuthRequest(outgoingAuthCredentials{
+					ID:       request.ID,
+					Username: username,
+					Password: password,
+				})
+			})
+			promptCanceller = func() { cancelAuthRequest(request.ID) }
+		})
+		promptCanceller = func() { cancelAuthRequest(request.ID) }
+	})
+}
+
+func cancelAuthRequest(id string) {
+	finishAuthRequest(outgoingAuthCredentials{ID: id, Cancel: true})
+}
+
+func finishAuthRequest(credentials outgoingAuthCredentials) {
+	marshalled, _ := json.Marshal(credentials)
+	sendMessageToWebExtension("/auth_credentials," + string(marshalled))
+	pendingAuthRequests = pendingAuthRequests[1:]
+	if len(pendingAuthRequests) > 0 {
+		promptForAuth(pendingAuthRequests[0])
+	}
+}
@@ -0,0 +1,95 @@
+package browsh
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// The HTTP server listens on every interface by default, so it can be locked down with
+// a token that clients send as `Authorization: Bearer <token>`, and served over TLS.
+// Without a certificate of its own, a self-signed one is made each time Browsh starts,
+// and its fingerprint printed so that clients can check it. It goes to STDERR, rather
+// than only the log, as logging is off by default and there's no TUI to show it in.
+func requireHTTPServerToken(next http.Handler) http.Handler {
+	if *httpServerToken == "" {
+		return next
+	}
+	expected := []byte("Bearer " + *httpServerToken)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		given := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(given, expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="browsh"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func httpServerTLSConfig() (*tls.Config, error) {
+	var certificate tls.Certificate
+	var err error
+	if *httpServerTLSCert != "" {
+		certificate, err = tls.LoadX509KeyPair(*httpServerTLSCert, *httpServerTLSKey)
+	} else {
+		certificate, err = selfSignedCertificate(time.Now())
+		if err == nil {
+			fingerprint := sha256.Sum256(certificate.Certificate[0])
+			message := fmt.Sprintf("Using a self-signed certificate with the SHA-256 fingerprint %X",
+				fingerprint)
+			fmt.Fprintln(os.Stderr, message)
+			logInfo(message)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{Certificates: []tls.Certificate{certificate}}, nil
+}
+
+func selfSignedCertificate(now time.Time) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	hostname, _ := os.Hostname()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Browsh"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
+	}
+	if hostname != "" && !strings.EqualFold(hostname, "localhost") {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+	if ip := net.ParseIP(*httpServerBind); ip != nil && !ip.IsUnspecified() {
+		template.IPAddresses = append(template.IPAddresses, ip)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,29 @@
+package browsh
+
+// With --pause-after the browser stops sending new frames once nothing has happened
+// for that many seconds, and tells the TTY. The TTY then stops drawing too, which
+// matters over SSH, where every change to the screen is sent to the terminal. Playing
+// videos don't count as nothing happening. Any key press or mouse event resumes
+// straight away, from the latest frame.
+var isCapturePaused = false
+
+const pausedLabel = " PAUSED "
+
+func handlePaused(state string) {
+	isPaused := state == "true"
+	if isPaused == isCapturePaused {
+		return
+	}
+	isCapturePaused = isPaused
+	renderCurrentTabWindow()
+}
+
+// Called for all of the user's input, as not all of it reaches the browser
+func resumeIfPaused() {
+	if !isCapturePaused {
+		return
+	}
+	isCapturePaused = false
+	sendMessageToWebExtension("/activity")
+	renderCurrentTabWindow()
+}
@@ -55,7 +55,11 @@ func (i *inputBox) renderURLBox() {
 	style := tcell.StyleDefault
 	style = style.Foreground(fgRGB).Background(bgRGB)
 	x := i.X
-	for _, c := range i.textToDisplay() {
+	textLength := utf8.RuneCountInString(i.text)
+	for index, c := range i.textToDisplay() {
+		if i.Type == "password" && index+i.xScroll < textLength {
+			c = '●'
+		}
 		screen.SetContent(x, i.Y, c, nil, style)
 		x++
 	}
@@ -129,7 +133,7 @@ func (i *inputBox) addCharacterToFrame(x int, y int, c rune) {
 // Different methods are used for containing and displaying overflowed text depending on the
 // size of the input box.
 func (i *inputBox) isMultiLine() bool {
-	if urlInputBox.isActive {
+	if isUIInputBoxActive() {
 		return false
 	}
 	return i.TagName == "TEXTAREA" || i.Type == "textbox"
@@ -166,6 +170,9 @@ func isLineBreak(character string) bool {
 }
 
 func (i *inputBox) sendInputBoxToBrowser() {
+	if isUIInputBoxActive() {
+		return
+	}
 	inputBoxMap := map[string]interface{}{
 		"id":   i.ID,
 		"text": i.text,
@@ -175,6 +182,10 @@ func (i *inputBox) sendInputBoxToBrowser() {
 }
 
 func (i *inputBox) handleEnterKey(modifier tcell.ModMask) {
+	if promptInputBox.isActive {
+		submitPrompt()
+		return
+	}
 	if urlInputBox.isActive {
 		if isNewEmptyTabActive() {
 			sendMessageToWebExtension("/new_tab," + i.text)
@@ -243,6 +254,8 @@ func handleInputBoxInput(ev *tcell.EventKey) {
 	}
 	if urlInputBox.isActive {
 		renderURLBar()
+	} else if promptInputBox.isActive {
+		renderPrompt()
 	} else {
 		renderCurrentTabWindow()
 	}
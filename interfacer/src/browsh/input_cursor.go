@@ -44,9 +44,10 @@ func (i *inputBox) getCoordsOfCursor() (int, int) {
 }
 
 func (i *inputBox) getCoordsOfIndex(index int) (int, int) {
-	xFrameOffset := CurrentTab.frame.xScroll
-	yFrameOffset := CurrentTab.frame.yScroll - uiHeight
-	if urlInputBox.isActive {
+	xFrameOffset, yFrameOffset := CurrentTab.frame.viewport.position()
+	paneTop, _ := focusedPaneArea()
+	yFrameOffset -= paneTop
+	if isUIInputBoxActive() {
 		xFrameOffset = 0
 		yFrameOffset = 0
 	}
@@ -100,6 +101,17 @@ func (i *inputBox) cursorInsertRune(theRune rune) {
 	i.sendInputBoxToBrowser()
 }
 
+// Like cursorInsertRune, but the browser is only sent the result once
+func (i *inputBox) cursorInsertText(text string) {
+	for _, theRune := range text {
+		start := i.text[:i.textCursor]
+		end := i.text[i.textCursor:]
+		i.text = start + string(theRune) + end
+		i.cursorRight()
+	}
+	i.sendInputBoxToBrowser()
+}
+
 func (i *inputBox) isCursorOverRightEdge() bool {
 	return i.textCursor-i.xScroll >= i.Width
 }
@@ -128,7 +140,7 @@ func (i *inputBox) getCharacterAt() string {
 }
 
 func (i *inputBox) putCursorAtEnd() {
-	i.textCursor = utf8.RuneCountInString(urlInputBox.text)
+	i.textCursor = utf8.RuneCountInString(i.text)
 	// TODO: Do for multiline
 }
 
@@ -0,0 +1,502 @@
+package browsh
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell"
+	"github.com/go-errors/errors"
+)
+
+// The names the DOM gives to keys that don't type a character, see:
+// https://developer.mozilla.org/en-US/docs/Web/API/KeyboardEvent/key/Key_Values
+// Tcell's diagonal keys come from a keypad without Num Lock, where they're the same
+// as these named keys.
+var domKeyNames = map[tcell.Key]string{
+	tcell.KeyBackspace:  "Backspace",
+	tcell.KeyTab:        "Tab",
+	tcell.KeyEnter:      "Enter",
+	tcell.KeyEscape:     "Escape",
+	tcell.KeyBackspace2: "Backspace",
+	tcell.KeyUp:         "ArrowUp",
+	tcell.KeyDown:       "ArrowDown",
+	tcell.KeyRight:      "ArrowRight",
+	tcell.KeyLeft:       "ArrowLeft",
+	tcell.KeyUpLeft:     "Home",
+	tcell.KeyUpRight:    "PageUp",
+	tcell.KeyDownLeft:   "End",
+	tcell.KeyDownRight:  "PageDown",
+	tcell.KeyCenter:     "Clear",
+	tcell.KeyPgUp:       "PageUp",
+	tcell.KeyPgDn:       "PageDown",
+	tcell.KeyHome:       "Home",
+	tcell.KeyEnd:        "End",
+	tcell.KeyInsert:     "Insert",
+	tcell.KeyDelete:     "Delete",
+	tcell.KeyHelp:       "Help",
+	tcell.KeyExit:       "Exit",
+	tcell.KeyClear:      "Clear",
+	tcell.KeyCancel:     "Cancel",
+	tcell.KeyPrint:      "PrintScreen",
+	tcell.KeyPause:      "Pause",
+	tcell.KeyBacktab:    "Tab",
+}
+
+// Translates a key press into the `key` value and modifiers of the KeyboardEvent that
+// the browser would have made for it. Tcell reports CTRL combinations as control
+// characters, so they need turning back into the letter or symbol that was pressed.
+func domKey(ev *tcell.EventKey) (string, tcell.ModMask) {
+	key := ev.Key()
+	modifiers := ev.Modifiers()
+	if name, ok := domKeyNames[key]; ok {
+		if key == tcell.KeyBacktab {
+			modifiers |= tcell.ModShift
+		}
+		return name, modifiers
+	}
+	switch {
+	case key == tcell.KeyRune:
+		return string(ev.Rune()), modifiers
+	case key >= tcell.KeyF1 && key <= tcell.KeyF64:
+		return fmt.Sprintf("F%d", key-tcell.KeyF1+1), modifiers
+	case key == tcell.KeyCtrlSpace:
+		return " ", modifiers | tcell.ModCtrl
+	case key >= tcell.KeyCtrlA && key <= tcell.KeyCtrlZ:
+		return string('a' + rune(key-tcell.KeyCtrlA)), modifiers | tcell.ModCtrl
+	case key >= tcell.KeyCtrlBackslash && key <= tcell.KeyCtrlUnderscore:
+		return string(`\]^_`[key-tcell.KeyCtrlBackslash]), modifiers | tcell.ModCtrl
+	}
+	return "Unidentified", modifiers
+}
+
+// Terminals only say when keys are pressed, not when they're let go, so a held key is
+// just the same key arriving again and again. Pages are told about these in the
+// KeyboardEvent's `repeat`, like when a key is held in a browser. But the doubled
+// letters of fast typing, or pasting, arrive close together too. So it's only a held
+// key once the same key arrives at the steady beat of a keyboard's auto-repeat, ie;
+// from the third time, as the first repeat comes after a longer delay.
+const (
+	keyRepeatInterval = 100 * time.Millisecond
+	// Pasted text arrives all at once, faster than any keyboard repeats
+	keyRepeatMinimum   = 5 * time.Millisecond
+	keyRepeatTolerance = 10 * time.Millisecond
+)
+
+type keyRepeatDetector struct {
+	key       tcell.Key
+	char      rune
+	modifiers tcell.ModMask
+	at        time.Time
+	// Between the last two of the same key, 0 when they weren't on a beat
+	interval time.Duration
+}
+
+var keyRepeats keyRepeatDetector
+
+func (d *keyRepeatDetector) isRepeat(ev *tcell.EventKey) bool {
+	return d.isRepeatAt(ev, ev.When())
+}
+
+func (d *keyRepeatDetector) isRepeatAt(ev *tcell.EventKey, at time.Time) bool {
+	isSame := ev.Key() == d.key && ev.Rune() == d.char && ev.Modifiers() == d.modifiers
+	interval := at.Sub(d.at)
+	isOnBeat := isSame && interval >= keyRepeatMinimum && interval < keyRepeatInterval
+	difference := interval - d.interval
+	if difference < 0 {
+		difference = -difference
+	}
+	isRepeat := isOnBeat && d.interval != 0 && difference <= keyRepeatTolerance
+	d.key, d.char, d.modifiers, d.at = ev.Key(), ev.Rune(), ev.Modifiers(), at
+	d.interval = 0
+	if isOnBeat {
+		d.interval = interval
+	}
+	return isRepeat
+}
+
+// Browsh's own keys can be changed with --keys, eg; `ctrl+alt+z=zoom-in,alt+q=none`,
+// which moves zooming in to CTRL+ALT+Z, and frees ALT+Q for pages to use. As commas
+// and plus signs separate things, those keys are called `comma` and `plus`. An action
+// can have more than one key, as `+` needs SHIFT on most keyboards, so `=` also zooms.
+// A key that's already used by another action has to be freed with `none` first.
+type keyBinding struct {
+	action string
+	chord  string
+}
+
+var defaultKeyBindings = []keyBinding{
+	{"quit", "ctrl+q"},
+	{"url-bar", "ctrl+l"},
+	{"new-tab", "ctrl+t"},
+	{"close-tab", "ctrl+w"},
+	{"next-tab", "tab"},
+	{"previous-tab", "shift+tab"},
+	{"tab-list", "alt+w"},
+	{"help", "f1"},
+	{"command-mode", "alt+x"},
+	{"monochrome", "alt+m"},
+	{"render-mode", "alt+v"},
+	{"brightness-up", "alt+)"},
+	{"brightness-down", "alt+("},
+	{"contrast-up", "alt+}"},
+	{"contrast-down", "alt+{"},
+	{"gamma-up", "alt+>"},
+	{"gamma-down", "alt+<"},
+	{"picture-reset", "alt+9"},
+	{"qr-code", "alt+q"},
+	{"select-url", "alt+h"},
+	{"find-link", "alt+l"},
+	{"follow-focus", "alt+f"},
+	{"copy-mode", "alt+c"},
+	{"clipboard-history", "alt+shift+c"},
+	{"middle-click", "alt+i"},
+	{"bookmark", "alt+d"},
+	{"bookmarks", "alt+r"},
+	{"history", "alt+y"},
+	{"downloads", "alt+j"},
+	{"find", "ctrl+f"},
+	{"find-next", "ctrl+g"},
+	{"find-previous", "alt+g"},
+	{"split", "alt+s"},
+	{"switch-pane", "alt+o"},
+	{"thumbnail", "alt+t"},
+	{"media-play-pause", "alt+k"},
+	{"media-back", "alt+comma"},
+	{"media-forward", "alt+."},
+	{"zoom-in", "alt+="},
+	{"zoom-in", "alt+plus"},
+	{"zoom-out", "alt+-"},
+	{"zoom-reset", "alt+0"},
+	{"zoom-preset", "alt+z"},
+	{"pan-left", "alt+shift+left"},
+	{"pan-right", "alt+shift+right"},
+	{"pan-up", "alt+shift+up"},
+	{"pan-down", "alt+shift+down"},
+	{"read-aloud", "alt+a"},
+	{"next-paragraph", "alt+n"},
+	{"bandwidth-meter", "alt+b"},
+	{"screenshot", "alt+p"},
+	{"user-agent", "alt+u"},
+	{"history-back", "alt+left"},
+	{"history-forward", "alt+right"},
+}
+
+type keyChord struct {
+	name      string
+	modifiers tcell.ModMask
+}
+
+var keyMap map[keyChord]string
+
+func keyActionNames() []string {
+	var names []string
+	for i, binding := range defaultKeyBindings {
+		if i == 0 || defaultKeyBindings[i-1].action != binding.action {
+			names = append(names, binding.action)
+		}
+	}
+	return names
+}
+
+func isKeyAction(action string) bool {
+	for _, binding := range defaultKeyBindings {
+		if binding.action == action {
+			return true
+		}
+	}
+	return false
+}
+
+func setupKeyBindings(changes string) error {
+	keyMap = make(map[keyChord]string)
+	for _, binding := range defaultKeyBindings {
+		chord, _ := parseKeyChord(binding.chord)
+		keyMap[chord] = binding.action
+	}
+	isRebound := make(map[string]bool)
+	for _, change := range strings.Split(changes, ",") {
+		if strings.TrimSpace(change) == "" {
+			continue
+		}
+		// The last `=`, as `alt+=` is a key
+		equals := strings.LastIndex(change, "=")
+		if equals < 1 {
+			return errors.New(fmt.Sprintf("'%s' should look like 'ctrl+alt+z=zoom-in'", change))
+		}
+		chord, err := parseKeyChord(change[:equals])
+		if err != nil {
+			return err
+		}
+		action := strings.TrimSpace(change[equals+1:])
+		if action == "none" {
+			delete(keyMap, chord)
+			continue
+		}
+		if !isKeyAction(action) {
+			return errors.New(fmt.Sprintf("there's no action called '%s', choose from: none, %s",
+				action, strings.Join(keyActionNames(), ", ")))
+		}
+		// Only the action's default keys are replaced, so that it can be given several
+		if !isRebound[action] {
+			for existing, existingAction := range keyMap {
+				if existingAction == action {
+					delete(keyMap, existing)
+				}
+			}
+			isRebound[action] = true
+		}
+		if existingAction, ok := keyMap[chord]; ok && existingAction != action {
+			return errors.New(fmt.Sprintf("%s is already used for '%s', so can't also be '%s', "+
+				"free it first with '%s=none'", chord, existingAction, action, chord))
+		}
+		keyMap[chord] = action
+	}
+	return nil
+}
+
+// For hints like "ALT+D bookmarks a page", which should follow --keys. Returns "" when
+// nothing is bound to the action, and the first key alphabetically when several are.
+func keyForAction(action string) string {
+	var keys []string
+	for chord, bound := range keyMap {
+		if bound == action {
+			keys = append(keys, strings.ToUpper(chord.String()))
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+	return keys[0]
+}
+
+// Names for keys that aren't characters. The DOM's own names work too.
+var keyChordAliases = map[string]string{
+	"comma": ",",
+	"plus":  "+",
+	"space": " ",
+	"esc":   "Escape",
+	"up":    "ArrowUp",
+	"down":  "ArrowDown",
+	"left":  "ArrowLeft",
+	"right": "ArrowRight",
+	"pgup":  "PageUp",
+	"pgdn":  "PageDown",
+}
+
+func parseKeyChord(text string) (keyChord, error) {
+	var chord keyChord
+	parts := strings.Split(strings.TrimSpace(text), "+")
+	for _, modifier := range parts[:len(parts)-1] {
+		switch strings.ToLower(modifier) {
+		case "ctrl":
+			chord.modifiers |= tcell.ModCtrl
+		case "alt":
+			chord.modifiers |= tcell.ModAlt
+		case "shift":
+			chord.modifiers |= tcell.ModShift
+		case "meta":
+			chord.modifiers |= tcell.ModMeta
+		default:
+			return chord, errors.New(fmt.Sprintf("'%s' in '%s' isn't ctrl, alt, shift or meta", modifier, text))
+		}
+	}
+	key := parts[len(parts)-1]
+	chord.name = keyChordName(key)
+	if chord.name == "" {
+		return chord, errors.New(fmt.Sprintf("'%s' in '%s' isn't a key Browsh knows", key, text))
+	}
+	// Terminals can't tell CTRL+Q from CTRL+SHIFT+Q
+	if chord.modifiers&tcell.ModCtrl != 0 {
+		chord.name = strings.ToLower(chord.name)
+		return chord, nil
+	}
+	// Otherwise SHIFT with a letter arrives as the capital letter, so `alt+shift+c` and
+	// `alt+C` are the same key
+	if isCasedLetter(chord.name) && chord.modifiers&tcell.ModShift != 0 {
+		chord.name = strings.ToUpper(chord.name)
+		chord.modifiers &^= tcell.ModShift
+	}
+	return chord, nil
+}
+
+func isCasedLetter(name string) bool {
+	return utf8.RuneCountInString(name) == 1 && strings.ToUpper(name) != strings.ToLower(name)
+}
+
+func keyChordName(key string) string {
+	if utf8.RuneCountInString(key) == 1 {
+		return key
+	}
+	lower := strings.ToLower(key)
+	if name, ok := keyChordAliases[lower]; ok {
+		return name
+	}
+	for _, name := range domKeyNames {
+		if strings.ToLower(name) == lower {
+			return name
+		}
+	}
+	var number int
+	if _, err := fmt.Sscanf(lower, "f%d", &number); err == nil && number >= 1 && number <= 64 {
+		return fmt.Sprintf("F%d", number)
+	}
+	return ""
+}
+
+// The same way the chord would be written in --keys
+func (chord keyChord) String() string {
+	var parts []string
+	modifiers := chord.modifiers
+	// Capital letters are written as SHIFT with the letter, so they can't be mistaken
+	// for the lower case letter once they're in upper case hints
+	if isCasedLetter(chord.name) && chord.name == strings.ToUpper(chord.name) {
+		modifiers |= tcell.ModShift
+	}
+	modifierNames := []string{"ctrl", "alt", "shift", "meta"}
+	for i, modifier := range []tcell.ModMask{tcell.ModCtrl, tcell.ModAlt, tcell.ModShift, tcell.ModMeta} {
+		if modifiers&modifier != 0 {
+			parts = append(parts, modifierNames[i])
+		}
+	}
+	name := chord.name
+	for alias, aliased := range keyChordAliases {
+		if aliased == name && utf8.RuneCountInString(name) == 1 {
+			name = alias
+		}
+	}
+	return strings.Join(append(parts, strings.ToLower(name)), "+")
+}
+
+func keyChordFromEvent(ev *tcell.EventKey) keyChord {
+	name, modifiers := domKey(ev)
+	// Shifted characters are already different characters
+	if ev.Key() == tcell.KeyRune {
+		modifiers &^= tcell.ModShift
+	}
+	return keyChord{name, modifiers}
+}
+
+// Returns whether the key was one of Browsh's own, rather than one for the page
+func handleKeyBinding(ev *tcell.EventKey) bool {
+	action, ok := keyMap[keyChordFromEvent(ev)]
+	if !ok {
+		return false
+	}
+	return runKeyAction(action)
+}
+
+func runKeyAction(action string) bool {
+	switch action {
+	case "quit":
+		quitBrowsh()
+	case "url-bar":
+		urlBarFocusToggle()
+	case "new-tab":
+		createNewEmptyTab()
+	case "close-tab":
+		removeTab(CurrentTab.ID)
+	case "next-tab":
+		nextTab()
+	case "previous-tab":
+		previousTab()
+	case "tab-list":
+		openTabList()
+	case "help":
+		openHelpTab()
+	case "command-mode":
+		toggleCommandMode()
+	case "monochrome":
+		toggleMonochromeMode()
+	case "render-mode":
+		cycleRenderMode()
+	case "brightness-up", "brightness-down", "contrast-up", "contrast-down", "gamma-up", "gamma-down",
+		"picture-reset":
+		changePicture(action)
+	case "qr-code":
+		showURLAsQRCode()
+	case "select-url":
+		selectNextURL()
+	case "find-link":
+		openLinkSearch()
+	case "follow-focus":
+		toggleFollowFocus()
+	case "middle-click":
+		middleClickAtMousePointer()
+	case "copy-mode":
+		startCopyMode()
+	case "clipboard-history":
+		openClipboardHistory()
+	case "bookmark":
+		toggleBookmark()
+	case "bookmarks":
+		openBookmarkList()
+	case "history":
+		openHistoryList()
+	case "downloads":
+		openDownloadList()
+	case "find":
+		openFind()
+	case "find-next":
+		findAgain(false)
+	case "find-previous":
+		findAgain(true)
+	case "split":
+		toggleSplit()
+	case "switch-pane":
+		switchPaneFocus()
+	case "thumbnail":
+		toggleThumbnail()
+	// Like K, J and L on YouTube
+	case "media-play-pause":
+		sendFeatureMessage("media", "/tab_command,/media,toggle")
+	case "media-back":
+		sendFeatureMessage("media", "/tab_command,/media,seek,-10")
+	case "media-forward":
+		sendFeatureMessage("media", "/tab_command,/media,seek,10")
+	// Many terminals don't forward the CTRL+mouse wheel that would otherwise be needed,
+	// and tmux and screen never do, so zooming has keys of its own
+	case "zoom-in":
+		sendFeatureMessage("zoom", "/zoom,in")
+	case "zoom-out":
+		sendFeatureMessage("zoom", "/zoom,out")
+	case "zoom-reset":
+		sendFeatureMessage("zoom", "/zoom,reset")
+	case "zoom-preset":
+		cycleZoomPreset()
+	case "pan-left":
+		panFrame(-1, 0)
+	case "pan-right":
+		panFrame(1, 0)
+	case "pan-up":
+		panFrame(0, -1)
+	case "pan-down":
+		panFrame(0, 1)
+	case "read-aloud":
+		toggleReadAloud()
+	case "next-paragraph":
+		pageReader.next()
+	case "bandwidth-meter":
+		toggleBandwidthMeter()
+	case "screenshot":
+		sendFeatureMessage("screenshot", "/screenshot")
+	case "user-agent":
+		sendFeatureMessage("user_agent", "/user_agent")
+	// Left and right move the cursor when typing
+	case "history-back", "history-forward":
+		if activeInputBox != nil {
+			return false
+		}
+		sendMessageToWebExtension("/tab_command,/" + strings.Replace(action, "-", "_", 1))
+	}
+	return true
+}
+
+func sendFeatureMessage(feature, message string) {
+	if requireWebextFeature(feature) {
+		sendMessageToWebExtension(message)
+	}
+}
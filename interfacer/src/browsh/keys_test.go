@@ -0,0 +1,222 @@
+package browsh
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestKeys(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Key tests")
+}
+
+var _ = Describe("DOM keys", func() {
+	It("should identify every key tcell can report", func() {
+		for key := tcell.KeyNUL; key <= tcell.KeyDEL; key++ {
+			if key > tcell.KeyCtrlUnderscore && key < tcell.KeyDEL {
+				continue
+			}
+			name, _ := domKey(tcell.NewEventKey(key, rune(key), tcell.ModNone))
+			Expect(name).NotTo(Equal("Unidentified"), "key %d", key)
+		}
+		for key := tcell.KeyUp; key <= tcell.KeyF64; key++ {
+			name, _ := domKey(tcell.NewEventKey(key, 0, tcell.ModNone))
+			Expect(name).NotTo(Equal("Unidentified"), "key %d", key)
+		}
+	})
+
+	It("should turn control characters back into CTRL combinations", func() {
+		name, modifiers := domKey(tcell.NewEventKey(tcell.KeyCtrlC, 3, tcell.ModNone))
+		Expect(name).To(Equal("c"))
+		Expect(modifiers).To(Equal(tcell.ModCtrl))
+		name, _ = domKey(tcell.NewEventKey(tcell.KeyCtrlBackslash, 28, tcell.ModCtrl))
+		Expect(name).To(Equal(`\`))
+	})
+
+	It("should keep plain keys that share codes with CTRL combinations", func() {
+		name, modifiers := domKey(tcell.NewEventKey(tcell.KeyRune, '\t', tcell.ModNone))
+		Expect(name).To(Equal("Tab"))
+		Expect(modifiers).To(Equal(tcell.ModNone))
+		name, _ = domKey(tcell.NewEventKey(tcell.KeyEscape, 27, tcell.ModNone))
+		Expect(name).To(Equal("Escape"))
+	})
+
+	It("should shift a backwards tab", func() {
+		name, modifiers := domKey(tcell.NewEventKey(tcell.KeyBacktab, 0, tcell.ModNone))
+		Expect(name).To(Equal("Tab"))
+		Expect(modifiers).To(Equal(tcell.ModShift))
+	})
+
+	It("should pass through non-ASCII characters", func() {
+		name, modifiers := domKey(tcell.NewEventKey(tcell.KeyRune, 'ß', tcell.ModAlt))
+		Expect(name).To(Equal("ß"))
+		Expect(modifiers).To(Equal(tcell.ModAlt))
+	})
+
+	It("should name function keys", func() {
+		name, _ := domKey(tcell.NewEventKey(tcell.KeyF12, 0, tcell.ModNone))
+		Expect(name).To(Equal("F12"))
+	})
+
+	It("should treat the same key arriving at a steady beat as a held key", func() {
+		var repeats keyRepeatDetector
+		start := time.Now()
+		down := tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+		at := func(milliseconds int) time.Time {
+			return start.Add(time.Duration(milliseconds) * time.Millisecond)
+		}
+		Expect(repeats.isRepeatAt(down, at(0))).To(BeFalse())
+		Expect(repeats.isRepeatAt(down, at(500))).To(BeFalse())
+		Expect(repeats.isRepeatAt(down, at(533))).To(BeFalse())
+		Expect(repeats.isRepeatAt(down, at(566))).To(BeTrue())
+		Expect(repeats.isRepeatAt(down, at(600))).To(BeTrue())
+		up := tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+		Expect(repeats.isRepeatAt(up, at(633))).To(BeFalse())
+	})
+
+	It("should not treat doubled letters as a held key", func() {
+		var repeats keyRepeatDetector
+		start := time.Now()
+		l := tcell.NewEventKey(tcell.KeyRune, 'l', tcell.ModNone)
+		// Typed quickly
+		Expect(repeats.isRepeatAt(l, start)).To(BeFalse())
+		Expect(repeats.isRepeatAt(l, start.Add(60*time.Millisecond))).To(BeFalse())
+		// Pasted
+		Expect(repeats.isRepeatAt(l, start.Add(time.Second))).To(BeFalse())
+		Expect(repeats.isRepeatAt(l, start.Add(time.Second+time.Microsecond))).To(BeFalse())
+		Expect(repeats.isRepeatAt(l, start.Add(time.Second+2*time.Microsecond))).To(BeFalse())
+	})
+})
+
+var _ = Describe("Key bindings", func() {
+	key := func(k tcell.Key, ch rune, mod tcell.ModMask) string {
+		return keyMap[keyChordFromEvent(tcell.NewEventKey(k, ch, mod))]
+	}
+
+	It("should have Browsh's usual keys by default", func() {
+		Expect(setupKeyBindings("")).To(Succeed())
+		Expect(key(tcell.KeyCtrlQ, 17, tcell.ModNone)).To(Equal("quit"))
+		Expect(key(tcell.KeyRune, 'q', tcell.ModAlt)).To(Equal("qr-code"))
+		Expect(key(tcell.KeyRune, ',', tcell.ModAlt)).To(Equal("media-back"))
+		Expect(key(tcell.KeyRune, '+', tcell.ModAlt|tcell.ModShift)).To(Equal("zoom-in"))
+		Expect(key(tcell.KeyLeft, 0, tcell.ModAlt)).To(Equal("history-back"))
+		Expect(key(tcell.KeyLeft, 0, tcell.ModAlt|tcell.ModShift)).To(Equal("pan-left"))
+		Expect(key(tcell.KeyF1, 0, tcell.ModNone)).To(Equal("help"))
+		Expect(key(tcell.KeyBacktab, 0, tcell.ModNone)).To(Equal("previous-tab"))
+		Expect(key(tcell.KeyRune, ')', tcell.ModAlt|tcell.ModShift)).To(Equal("brightness-up"))
+		Expect(key(tcell.KeyRune, 'p', tcell.ModAlt)).To(Equal("screenshot"))
+		Expect(key(tcell.KeyRune, 'u', tcell.ModAlt)).To(Equal("user-agent"))
+		Expect(key(tcell.KeyRune, 'q', tcell.ModNone)).To(Equal(""))
+	})
+
+	It("should move an action to a new key", func() {
+		Expect(setupKeyBindings("ctrl+alt+z=zoom-in, alt+e=quit")).To(Succeed())
+		Expect(key(tcell.KeyCtrlZ, 26, tcell.ModAlt)).To(Equal("zoom-in"))
+		Expect(key(tcell.KeyRune, '+', tcell.ModAlt)).To(Equal(""))
+		Expect(key(tcell.KeyRune, '=', tcell.ModAlt)).To(Equal(""))
+		Expect(key(tcell.KeyRune, 'e', tcell.ModAlt)).To(Equal("quit"))
+		Expect(key(tcell.KeyCtrlQ, 17, tcell.ModNone)).To(Equal(""))
+	})
+
+	It("should name an action's key in hints", func() {
+		Expect(setupKeyBindings("")).To(Succeed())
+		Expect(keyForAction("bookmark")).To(Equal("ALT+D"))
+		Expect(keyForAction("zoom-in")).To(Equal("ALT+="))
+		Expect(setupKeyBindings("ctrl+b=bookmark,alt+i=none")).To(Succeed())
+		Expect(keyForAction("bookmark")).To(Equal("CTRL+B"))
+		Expect(keyForAction("middle-click")).To(Equal(""))
+	})
+
+	It("should tell capital letters apart from small ones", func() {
+		Expect(setupKeyBindings("")).To(Succeed())
+		Expect(key(tcell.KeyRune, 'C', tcell.ModAlt)).To(Equal("clipboard-history"))
+		Expect(key(tcell.KeyRune, 'c', tcell.ModAlt)).To(Equal("copy-mode"))
+		Expect(keyForAction("clipboard-history")).To(Equal("ALT+SHIFT+C"))
+		Expect(keyForAction("copy-mode")).To(Equal("ALT+C"))
+		for _, change := range []string{"alt+C=find", "alt+shift+c=find"} {
+			err := setupKeyBindings(change)
+			Expect(err).To(MatchError(ContainSubstring("'clipboard-history'")))
+			suggestion := regexp.MustCompile(`free it first with '(.*)'`).FindStringSubmatch(err.Error())
+			Expect(suggestion).To(HaveLen(2))
+			Expect(setupKeyBindings(suggestion[1] + "," + change)).To(Succeed())
+			Expect(key(tcell.KeyRune, 'C', tcell.ModAlt)).To(Equal("find"))
+			Expect(key(tcell.KeyRune, 'c', tcell.ModAlt)).To(Equal("copy-mode"))
+		}
+	})
+
+	It("should give an action several new keys", func() {
+		Expect(setupKeyBindings("ctrl+x=quit,ctrl+y=quit")).To(Succeed())
+		Expect(key(tcell.KeyCtrlX, 24, tcell.ModNone)).To(Equal("quit"))
+		Expect(key(tcell.KeyCtrlY, 25, tcell.ModNone)).To(Equal("quit"))
+		Expect(key(tcell.KeyCtrlQ, 17, tcell.ModNone)).To(Equal(""))
+	})
+
+	It("should refuse to take a key from another action", func() {
+		err := setupKeyBindings("alt+q=quit")
+		Expect(err).To(MatchError(ContainSubstring("'qr-code'")))
+		Expect(err).To(MatchError(ContainSubstring("'quit'")))
+		Expect(setupKeyBindings("alt+p=middle-click")).To(MatchError(ContainSubstring("'screenshot'")))
+		Expect(setupKeyBindings("alt+q=none,alt+q=quit")).To(Succeed())
+		Expect(key(tcell.KeyRune, 'q', tcell.ModAlt)).To(Equal("quit"))
+	})
+
+	It("should free keys for pages to use", func() {
+		Expect(setupKeyBindings("alt+q=none,tab=none")).To(Succeed())
+		Expect(key(tcell.KeyRune, 'q', tcell.ModAlt)).To(Equal(""))
+		Expect(key(tcell.KeyTab, 9, tcell.ModNone)).To(Equal(""))
+	})
+
+	It("should run ALT's actions from plain keys in command mode", func() {
+		Expect(setupKeyBindings("")).To(Succeed())
+		chord := func(k tcell.Key, ch rune) keyChord {
+			return keyChordFromEvent(tcell.NewEventKey(k, ch, tcell.ModNone))
+		}
+		Expect(commandModeAction(chord(tcell.KeyRune, 'q'))).To(Equal("qr-code"))
+		Expect(commandModeAction(chord(tcell.KeyTab, 9))).To(Equal("next-tab"))
+		Expect(commandModeAction(chord(tcell.KeyRune, 'x'))).To(Equal("command-mode"))
+		Expect(commandModeAction(chord(tcell.KeyRune, 'e'))).To(Equal(""))
+	})
+
+	It("should describe keys the way --keys is written", func() {
+		chord := keyChordFromEvent(tcell.NewEventKey(tcell.KeyCtrlL, 12, tcell.ModNone))
+		Expect(chord.String()).To(Equal("ctrl+l"))
+		chord = keyChordFromEvent(tcell.NewEventKey(tcell.KeyRune, ',', tcell.ModAlt))
+		Expect(chord.String()).To(Equal("alt+comma"))
+		chord = keyChordFromEvent(tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModAlt))
+		Expect(chord.String()).To(Equal("alt+arrowleft"))
+	})
+
+	It("should explain bad changes", func() {
+		Expect(setupKeyBindings("hyper+z=quit")).To(MatchError(ContainSubstring("'hyper'")))
+		Expect(setupKeyBindings("alt+z=fly")).To(MatchError(ContainSubstring("'fly'")))
+		Expect(setupKeyBindings("alt+z")).To(MatchError(ContainSubstring("should look like")))
+		Expect(setupKeyBindings("alt+banana=quit")).To(MatchError(ContainSubstring("'banana'")))
+	})
+
+	AfterEach(func() {
+		setupKeyBindings("")
+	})
+})
+
+var _ = Describe("Mouse buttons", func() {
+	AfterEach(func() {
+		setupMouseButtons("")
+	})
+
+	It("should swap buttons around", func() {
+		Expect(setupMouseButtons("right=middle, middle=none")).To(Succeed())
+		Expect(remapMouseButtons(tcell.Button3)).To(Equal(tcell.Button2))
+		Expect(remapMouseButtons(tcell.Button2)).To(Equal(tcell.ButtonNone))
+		Expect(remapMouseButtons(tcell.Button1 | tcell.WheelUp)).To(Equal(tcell.Button1 | tcell.WheelUp))
+	})
+
+	It("should explain bad changes", func() {
+		Expect(setupMouseButtons("right")).To(MatchError(ContainSubstring("should look like")))
+		Expect(setupMouseButtons("fourth=left")).To(MatchError(ContainSubstring("'fourth=left'")))
+	})
+})
@@ -0,0 +1,120 @@
+package browsh
+
+import (
+	"crypto/subtle"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// Kiosk mode is for public terminals. Only the keys and mouse actions in --kiosk-keys
+// reach the page, none of Browsh's own keys work, so there's no URL bar or new tabs,
+// and the quit key, CTRL+Q unless --keys moves it, asks for the passphrase in
+// $BROWSH_KIOSK_PASSPHRASE. The passphrase is read from the environment so that it
+// doesn't show up in `ps`.
+const kioskPassphraseVariable = "BROWSH_KIOSK_PASSPHRASE"
+
+func isPlainKey(key tcell.Key) func(ev *tcell.EventKey) bool {
+	return func(ev *tcell.EventKey) bool {
+		return ev.Key() == key && ev.Modifiers() == 0
+	}
+}
+
+var kioskKeyMatchers = map[string]func(ev *tcell.EventKey) bool{
+	"up":    isPlainKey(tcell.KeyUp),
+	"down":  isPlainKey(tcell.KeyDown),
+	"left":  isPlainKey(tcell.KeyLeft),
+	"right": isPlainKey(tcell.KeyRight),
+	"enter": isPlainKey(tcell.KeyEnter),
+	"tab":   isPlainKey(tcell.KeyTab),
+	"esc":   isPlainKey(tcell.KeyEscape),
+	"pgup":  isPlainKey(tcell.KeyPgUp),
+	"pgdn":  isPlainKey(tcell.KeyPgDn),
+	"home":  isPlainKey(tcell.KeyHome),
+	"end":   isPlainKey(tcell.KeyEnd),
+	"backspace": func(ev *tcell.EventKey) bool {
+		return (ev.Key() == tcell.KeyBackspace || ev.Key() == tcell.KeyBackspace2) &&
+			ev.Modifiers() == 0
+	},
+	// Typing into the page's forms, shift is fine for capitals
+	"text": func(ev *tcell.EventKey) bool {
+		return ev.Key() == tcell.KeyRune && ev.Modifiers()&^tcell.ModShift == 0
+	},
+}
+
+// Mouse actions rather than keys
+var kioskMouseActions = []string{"click", "scroll"}
+
+func kioskKeyNames() []string {
+	names := append([]string{}, kioskMouseActions...)
+	for name := range kioskKeyMatchers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isKioskKeyName(name string) bool {
+	if _, ok := kioskKeyMatchers[name]; ok {
+		return true
+	}
+	for _, action := range kioskMouseActions {
+		if name == action {
+			return true
+		}
+	}
+	return false
+}
+
+func isKioskActionAllowed(name string) bool {
+	for _, allowed := range strings.Split(*kioskKeys, ",") {
+		if strings.TrimSpace(allowed) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isKioskKeyAllowed(ev *tcell.EventKey) bool {
+	for name, matcher := range kioskKeyMatchers {
+		if matcher(ev) && isKioskActionAllowed(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mouse movements, and releasing a button, are part of clicking
+func isKioskMouseEventAllowed(button tcell.ButtonMask) bool {
+	if button&(tcell.WheelUp|tcell.WheelDown|tcell.WheelLeft|tcell.WheelRight) != 0 {
+		return isKioskActionAllowed("scroll")
+	}
+	return isKioskActionAllowed("click")
+}
+
+func handleKioskKeyPress(ev *tcell.EventKey) {
+	if keyMap[keyChordFromEvent(ev)] == "quit" {
+		openPasswordPrompt("Passphrase to quit: ", checkKioskPassphrase)
+		return
+	}
+	if !isKioskKeyAllowed(ev) {
+		return
+	}
+	forwardKeyPress(ev)
+	if activeInputBox != nil {
+		handleInputBoxInput(ev)
+	} else {
+		handleScrolling(ev)
+	}
+}
+
+func checkKioskPassphrase(attempt string) {
+	passphrase := os.Getenv(kioskPassphraseVariable)
+	if passphrase != "" && subtle.ConstantTimeCompare([]byte(attempt), []byte(passphrase)) == 1 {
+		quitBrowsh()
+		return
+	}
+	sendMessageToWebExtension("/status,Wrong passphrase")
+}
@@ -0,0 +1,95 @@
+package browsh
+
+import (
+	"os"
+	"testing"
+
+	"github.com/gdamore/tcell"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestKiosk(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Kiosk tests")
+}
+
+var _ = Describe("Kiosk mode", func() {
+	var originalKeys string
+
+	BeforeEach(func() {
+		originalKeys = *kioskKeys
+	})
+
+	AfterEach(func() {
+		*kioskKeys = originalKeys
+		*isKioskMode = false
+		os.Unsetenv(kioskPassphraseVariable)
+	})
+
+	It("should only allow the listed keys", func() {
+		*kioskKeys = "up,enter"
+		Expect(isKioskKeyAllowed(tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone))).To(BeTrue())
+		Expect(isKioskKeyAllowed(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone))).To(BeFalse())
+		Expect(isKioskKeyAllowed(tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone))).To(BeFalse())
+	})
+
+	It("should never allow keys with modifiers, like Browsh's own", func() {
+		*kioskKeys = "text,enter"
+		Expect(isKioskKeyAllowed(tcell.NewEventKey(tcell.KeyRune, 'A', tcell.ModShift))).To(BeTrue())
+		Expect(isKioskKeyAllowed(tcell.NewEventKey(tcell.KeyRune, 'p', tcell.ModAlt))).To(BeFalse())
+		Expect(isKioskKeyAllowed(tcell.NewEventKey(tcell.KeyCtrlL, 0, tcell.ModCtrl))).To(BeFalse())
+		Expect(isKioskKeyAllowed(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModAlt))).To(BeFalse())
+	})
+
+	It("should allow clicking and scrolling separately", func() {
+		*kioskKeys = "scroll"
+		Expect(isKioskMouseEventAllowed(tcell.WheelDown)).To(BeTrue())
+		Expect(isKioskMouseEventAllowed(tcell.Button1)).To(BeFalse())
+		Expect(isKioskMouseEventAllowed(tcell.ButtonNone)).To(BeFalse())
+	})
+
+	It("should need a passphrase and known keys", func() {
+		*isKioskMode = true
+		*kioskKeys = "up,teleport"
+		Expect(validateFlags()).To(ConsistOf(
+			ContainSubstring("BROWSH_KIOSK_PASSPHRASE"),
+			ContainSubstring("unknown key 'teleport'")))
+		os.Setenv(kioskPassphraseVariable, "letmeout")
+		*kioskKeys = "up,click"
+		Expect(validateFlags()).To(BeEmpty())
+	})
+
+	It("should still ask for the passphrase when CTRL+Q is pressed twice", func() {
+		*isKioskMode = true
+		Expect(setupKeyBindings("")).To(Succeed())
+		newTab(1)
+		CurrentTab = Tabs[1]
+		simScreen := tcell.NewSimulationScreen("UTF-8")
+		simScreen.Init()
+		simScreen.SetSize(20, 5)
+		screen = simScreen
+		defer func() {
+			closePrompt()
+			CurrentTab = nil
+			screen = nil
+		}()
+		ctrlQ := tcell.NewEventKey(tcell.KeyCtrlQ, 17, tcell.ModCtrl)
+		handleUserKeyPress(ctrlQ)
+		handleUserKeyPress(ctrlQ)
+		Expect(promptInputBox.isActive).To(BeTrue())
+		Expect(promptLabel).To(Equal("Passphrase to quit: "))
+	})
+
+	It("should refuse to upload files", func() {
+		*isKioskMode = true
+		handleFileInputRequest(`{"id": "1"}`)
+		Expect(promptInputBox.isActive).To(BeFalse())
+	})
+
+	It("should reject certificate errors rather than ask", func() {
+		*isKioskMode = true
+		handleCertError(`{"url": "https://expired.example.com/", "error": "SEC_ERROR_EXPIRED_CERTIFICATE"}`)
+		Expect(isCertErrorShown()).To(BeFalse())
+	})
+})
@@ -0,0 +1,88 @@
+package browsh
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// With --measure-latency, each key press sent to the browser is timed until a frame
+// arrives that changes the screen. That's the whole round trip: the websocket, the
+// page reacting, rendering, and the frame coming back. It's useful for comparing
+// machines, Firefox versions and network links.
+type latencyMeter struct {
+	sync.Mutex
+	pressedAt time.Time
+	samples   []time.Duration
+}
+
+// Plenty of key presses don't change anything on screen, so don't wait forever for
+// them, otherwise an unrelated change much later would be measured.
+const latencyMeasurementTimeout = 5 * time.Second
+
+var inputLatency = &latencyMeter{}
+
+// Only the first of several quick key presses is timed, as that's the one the user
+// is waiting on.
+func (m *latencyMeter) keyPressed(at time.Time) {
+	m.Lock()
+	defer m.Unlock()
+	if m.pressedAt.IsZero() || at.Sub(m.pressedAt) > latencyMeasurementTimeout {
+		m.pressedAt = at
+	}
+}
+
+// Returns whether a key press was waiting on this change
+func (m *latencyMeter) screenChanged(at time.Time) (time.Duration, bool) {
+	m.Lock()
+	defer m.Unlock()
+	if m.pressedAt.IsZero() {
+		return 0, false
+	}
+	latency := at.Sub(m.pressedAt)
+	m.pressedAt = time.Time{}
+	if latency > latencyMeasurementTimeout {
+		return 0, false
+	}
+	m.samples = append(m.samples, latency)
+	return latency, true
+}
+
+func (m *latencyMeter) summary() string {
+	m.Lock()
+	defer m.Unlock()
+	if len(m.samples) == 0 {
+		return "Input latency: no key presses changed the screen"
+	}
+	sorted := append([]time.Duration{}, m.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p int) time.Duration {
+		return sorted[(len(sorted)-1)*p/100]
+	}
+	return fmt.Sprintf("Input latency over %d key presses: min %s, median %s, 95th %s, max %s",
+		len(sorted),
+		sorted[0].Round(time.Millisecond),
+		percentile(50).Round(time.Millisecond),
+		percentile(95).Round(time.Millisecond),
+		sorted[len(sorted)-1].Round(time.Millisecond))
+}
+
+// Frames for other tabs, like the one in the split pane, aren't what the key press was
+// waiting on
+func maybeMeasureLatency(tabID int) {
+	if !(*isLatencyMeasured || isMetricsEnabled()) || CurrentTab == nil || CurrentTab.ID != tabID ||
+		!CurrentTab.frame.isChanged {
+		return
+	}
+	latency, ok := inputLatency.screenChanged(time.Now())
+	if !ok {
+		return
+	}
+	if isMetricsEnabled() {
+		inputLatencyHistogram.observe(latency)
+	}
+	if *isLatencyMeasured {
+		sendMessageToWebExtension("/status," + inputLatency.summary())
+	}
+}
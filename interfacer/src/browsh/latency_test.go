@@ -0,0 +1,73 @@
+package browsh
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestLatency(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Latency tests")
+}
+
+var _ = Describe("Measuring input latency", func() {
+	var meter *latencyMeter
+	var start time.Time
+
+	BeforeEach(func() {
+		meter = &latencyMeter{}
+		start = time.Now()
+	})
+
+	It("should time from the first key press to the screen changing", func() {
+		meter.keyPressed(start)
+		meter.keyPressed(start.Add(20 * time.Millisecond))
+		latency, ok := meter.screenChanged(start.Add(50 * time.Millisecond))
+		Expect(ok).To(BeTrue())
+		Expect(latency).To(Equal(50 * time.Millisecond))
+	})
+
+	It("should ignore changes that no key press is waiting on", func() {
+		_, ok := meter.screenChanged(start)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should give up on key presses that change nothing", func() {
+		meter.keyPressed(start)
+		_, ok := meter.screenChanged(start.Add(latencyMeasurementTimeout + time.Second))
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should summarise the measurements", func() {
+		for _, ms := range []int{40, 10, 30, 20, 100} {
+			meter.keyPressed(start)
+			meter.screenChanged(start.Add(time.Duration(ms) * time.Millisecond))
+		}
+		Expect(meter.summary()).To(Equal(
+			"Input latency over 5 key presses: min 10ms, median 30ms, 95th 40ms, max 100ms"))
+	})
+
+	It("should only time frames for the current tab", func() {
+		*isLatencyMeasured = true
+		newTab(1)
+		newTab(2)
+		CurrentTab = Tabs[1]
+		Tabs[1].frame.isChanged = true
+		Tabs[2].frame.isChanged = true
+		defer func() {
+			*isLatencyMeasured = false
+			CurrentTab = nil
+			Tabs = make(map[int]*tab)
+			tabsOrder = nil
+			inputLatency = &latencyMeter{}
+		}()
+		inputLatency.keyPressed(time.Now())
+		maybeMeasureLatency(2)
+		Expect(inputLatency.samples).To(BeEmpty())
+		maybeMeasureLatency(1)
+		Expect(inputLatency.samples).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,203 @@
+package browsh
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Browsh logs to ./debug.log with --debug, or elsewhere with --log-file, which can
+// also be 'stderr' or 'syslog'. --log-level chooses how much is logged, which is
+// everything with --debug, otherwise info and above. 'stderr' is only for the HTTP
+// server, as in the TTY it would be written over the top of the page. Log files are
+// kept open and rotated once they reach --log-max-size, keeping one old file with a
+// `.1` suffix. A log that can't be written to is given up on, rather than taking
+// the whole session down with it.
+const (
+	logLevelDebug = iota
+	logLevelInfo
+	logLevelWarn
+	logLevelError
+	logLevelOff
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+type logger struct {
+	sync.Mutex
+	level  int
+	writer io.Writer
+	// Only set when logging to a file, which can be rotated
+	file    *os.File
+	path    string
+	size    int64
+	maxSize int64
+	// Why logging stopped, if it did
+	failure error
+}
+
+var browshLog = &logger{level: logLevelOff}
+
+// Writers, like syslog, that keep the level themselves rather than in the line
+type levelWriter interface {
+	writeLevel(level int, message string) error
+}
+
+func parseLogLevel(name string) (int, bool) {
+	for level, levelName := range logLevelNames {
+		if strings.ToLower(name) == levelName {
+			return level, true
+		}
+	}
+	return logLevelOff, false
+}
+
+func setupLogging() {
+	level := logLevelInfo
+	if *isDebug {
+		level = logLevelDebug
+	}
+	if *logLevel != "" {
+		level, _ = parseLogLevel(*logLevel)
+	}
+	var err error
+	switch {
+	case *logDestination == "stderr":
+		err = browshLog.start(level, os.Stderr)
+	case *logDestination == "syslog":
+		var writer io.Writer
+		if writer, err = newSyslogWriter(); err == nil {
+			err = browshLog.start(level, writer)
+		}
+	// The HTTP server has always logged to STDOUT
+	case *logDestination == "" && *IsHTTPServer && !IsTesting:
+		err = browshLog.start(level, os.Stdout)
+	default:
+		err = browshLog.startFile(level, logFilePath(), int64(*logMaxSize)*1024*1024)
+	}
+	if err != nil {
+		Shutdown(err)
+	}
+}
+
+func logFilePath() string {
+	if *logDestination != "" {
+		return *logDestination
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		Shutdown(err)
+	}
+	path := filepath.Join(dir, "debug.log")
+	fmt.Println("Logging to: " + path)
+	// Each --debug session starts a fresh log
+	os.Truncate(path, 0)
+	return path
+}
+
+func (l *logger) start(level int, writer io.Writer) error {
+	l.Lock()
+	defer l.Unlock()
+	l.level = level
+	l.writer = writer
+	return nil
+}
+
+func (l *logger) startFile(level int, path string, maxSize int64) error {
+	l.Lock()
+	defer l.Unlock()
+	l.level = level
+	l.path = path
+	l.maxSize = maxSize
+	return l.openFile()
+}
+
+func (l *logger) openFile() error {
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file = file
+	l.writer = file
+	l.size = info.Size()
+	return nil
+}
+
+func (l *logger) rotate() error {
+	l.file.Close()
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+	return l.openFile()
+}
+
+func (l *logger) write(level int, message string) {
+	l.Lock()
+	defer l.Unlock()
+	if level < l.level || l.writer == nil {
+		return
+	}
+	if writer, ok := l.writer.(levelWriter); ok {
+		if err := writer.writeLevel(level, message); err != nil {
+			l.stop(err)
+		}
+		return
+	}
+	line := fmt.Sprintf("%s %s %s\n",
+		time.Now().Format("2006-01-02T15:04:05.000"), strings.ToUpper(logLevelNames[level]), message)
+	if l.file != nil && l.maxSize > 0 && l.size+int64(len(line)) > l.maxSize {
+		if err := l.rotate(); err != nil {
+			l.stop(err)
+			return
+		}
+	}
+	count, err := io.WriteString(l.writer, line)
+	l.size += int64(count)
+	if err != nil {
+		l.stop(err)
+	}
+}
+
+func (l *logger) stop(err error) {
+	l.failure = err
+	l.level = logLevelOff
+	l.writer = nil
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+}
+
+// Returns why logging stopped early, if it did
+func (l *logger) close() error {
+	l.Lock()
+	defer l.Unlock()
+	failure := l.failure
+	l.stop(nil)
+	l.failure = nil
+	return failure
+}
+
+func logInfo(message string) {
+	browshLog.write(logLevelInfo, message)
+}
+
+func logWarn(message string) {
+	browshLog.write(logLevelWarn, message)
+}
+
+// For failures that don't stop Browsh but are worth knowing about. They're counted
+// too, for --stats-file.
+func logError(message string) {
+	countStat(&stats.Errors, 1)
+	browshLog.write(logLevelError, message)
+}
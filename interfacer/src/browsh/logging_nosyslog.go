@@ -0,0 +1,14 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package browsh
+
+import (
+	"io"
+
+	"github.com/go-errors/errors"
+)
+
+func newSyslogWriter() (io.Writer, error) {
+	return nil, errors.New("Syslog isn't available on this system, use --log-file instead")
+}
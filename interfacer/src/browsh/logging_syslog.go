@@ -0,0 +1,34 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package browsh
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// Syslog has its own timestamps and levels, so it's only sent the message
+type syslogWriter struct {
+	*syslog.Writer
+}
+
+func newSyslogWriter() (io.Writer, error) {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, "browsh")
+	if err != nil {
+		return nil, err
+	}
+	return syslogWriter{writer}, nil
+}
+
+func (w syslogWriter) writeLevel(level int, message string) error {
+	switch level {
+	case logLevelDebug:
+		return w.Debug(message)
+	case logLevelInfo:
+		return w.Info(message)
+	case logLevelWarn:
+		return w.Warning(message)
+	}
+	return w.Err(message)
+}
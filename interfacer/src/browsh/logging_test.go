@@ -0,0 +1,95 @@
+package browsh
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestLogging(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Logging tests")
+}
+
+var _ = Describe("Logging", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "browsh-logging")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should parse level names", func() {
+		level, ok := parseLogLevel("WARN")
+		Expect(ok).To(BeTrue())
+		Expect(level).To(Equal(logLevelWarn))
+		_, ok = parseLogLevel("verbose")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should only log at or above its level", func() {
+		var out bytes.Buffer
+		log := &logger{}
+		log.start(logLevelInfo, &out)
+		log.write(logLevelDebug, "hidden")
+		log.write(logLevelInfo, "shown")
+		log.write(logLevelError, "broken")
+		Expect(out.String()).NotTo(ContainSubstring("hidden"))
+		Expect(out.String()).To(ContainSubstring("INFO shown"))
+		Expect(out.String()).To(ContainSubstring("ERROR broken"))
+	})
+
+	It("should give writers with levels of their own just the message", func() {
+		out := &levelledWriter{}
+		log := &logger{}
+		log.start(logLevelDebug, out)
+		log.write(logLevelWarn, "careful")
+		Expect(out.levels).To(Equal([]int{logLevelWarn}))
+		Expect(out.messages).To(Equal([]string{"careful"}))
+	})
+
+	It("should rotate the log file once it's too big", func() {
+		path := filepath.Join(dir, "browsh.log")
+		log := &logger{}
+		Expect(log.startFile(logLevelDebug, path, 150)).To(Succeed())
+		log.write(logLevelDebug, "the first message, which is quite long")
+		log.write(logLevelDebug, "the second message, which is quite long")
+		log.write(logLevelDebug, "the third message")
+		Expect(log.close()).To(BeNil())
+		current, _ := ioutil.ReadFile(path)
+		old, _ := ioutil.ReadFile(path + ".1")
+		Expect(string(current)).To(ContainSubstring("third"))
+		Expect(string(old)).To(ContainSubstring("first"))
+		Expect(string(old)).To(ContainSubstring("second"))
+	})
+
+	It("should stop logging rather than fail when the log can't be written", func() {
+		path := filepath.Join(dir, "browsh.log")
+		log := &logger{}
+		Expect(log.startFile(logLevelDebug, path, 0)).To(Succeed())
+		log.file.Close()
+		log.write(logLevelError, "lost")
+		log.write(logLevelError, "also lost")
+		Expect(log.close()).NotTo(BeNil())
+	})
+})
+
+type levelledWriter struct {
+	bytes.Buffer
+	levels   []int
+	messages []string
+}
+
+func (w *levelledWriter) writeLevel(level int, message string) error {
+	w.levels = append(w.levels, level)
+	w.messages = append(w.messages, message)
+	return nil
+}
@@ -0,0 +1,124 @@
+package browsh
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// With --metrics, the session statistics and some timings are served for Prometheus
+// to scrape, for finding out why Browsh feels slow, eg; over SSH. The timings split a
+// key press's round trip into the parts that happen in the TTY and the rest, which is
+// the websocket, the browser and the page.
+type histogram struct {
+	sync.Mutex
+	name    string
+	help    string
+	buckets []float64
+	counts  []int64
+	sum     float64
+	count   int64
+}
+
+// In seconds, from about a frame at 1000fps up to the latency timeout
+var latencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+var (
+	inputHandlingHistogram = newHistogram("browsh_input_handling_seconds",
+		"Time the TTY takes to handle a key press, mouse event or resize")
+	frameParsingHistogram = newHistogram("browsh_frame_parsing_seconds",
+		"Time to parse a frame from the browser")
+	renderingHistogram = newHistogram("browsh_rendering_seconds",
+		"Time to draw the current tab in the terminal")
+	inputLatencyHistogram = newHistogram("browsh_input_latency_seconds",
+		"Time from a key press to a frame that changes the screen")
+	histograms = []*histogram{
+		inputHandlingHistogram,
+		frameParsingHistogram,
+		renderingHistogram,
+		inputLatencyHistogram,
+	}
+)
+
+func newHistogram(name, help string) *histogram {
+	return &histogram{
+		name:    name,
+		help:    help,
+		buckets: latencyBuckets,
+		counts:  make([]int64, len(latencyBuckets)),
+	}
+}
+
+func isMetricsEnabled() bool {
+	return *metricsAddress != ""
+}
+
+func (h *histogram) observe(duration time.Duration) {
+	seconds := duration.Seconds()
+	h.Lock()
+	defer h.Unlock()
+	for i, bucket := range h.buckets {
+		if seconds <= bucket {
+			h.counts[i]++
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+func (h *histogram) observeSince(start time.Time) {
+	if isMetricsEnabled() {
+		h.observe(time.Since(start))
+	}
+}
+
+func (h *histogram) write(w io.Writer) {
+	h.Lock()
+	defer h.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for i, bucket := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", h.name, bucket, h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(w, "%s_sum %g\n%s_count %d\n", h.name, h.sum, h.name, h.count)
+}
+
+func writeCounter(w io.Writer, name, help string, counter *int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n",
+		name, help, name, name, atomic.LoadInt64(counter))
+}
+
+func writeMetrics(w io.Writer) {
+	writeCounter(w, "browsh_frames_received_total", "Frames sent by the browser", &stats.FramesReceived)
+	writeCounter(w, "browsh_frames_dropped_total", "Frames that couldn't be used", &stats.FramesDropped)
+	writeCounter(w, "browsh_frames_rendered_total", "Times the terminal was drawn", &stats.FramesRendered)
+	writeCounter(w, "browsh_browser_received_bytes_total", "Bytes from the browser", &stats.BytesFromBrowser)
+	writeCounter(w, "browsh_browser_sent_bytes_total", "Bytes to the browser", &stats.BytesToBrowser)
+	writeCounter(w, "browsh_events_injected_total", "Key presses and mouse events sent to pages", &stats.EventsInjected)
+	writeCounter(w, "browsh_errors_total", "Errors that didn't stop Browsh", &stats.Errors)
+	for _, h := range histograms {
+		h.write(w)
+	}
+}
+
+// Separate from the HTTP server mode's server, as it's usually only for localhost
+func startMetricsServer() {
+	defer recoverAndShutdown()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writeMetrics(w)
+	})
+	address := *metricsAddress
+	if !strings.Contains(address, ":") {
+		address = "localhost:" + address
+	}
+	logInfo("Serving metrics on http://" + address + "/metrics")
+	if err := http.ListenAndServe(address, mux); err != nil {
+		logError("Metrics server: " + err.Error())
+	}
+}
@@ -0,0 +1,113 @@
+package browsh
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell"
+	"github.com/go-errors/errors"
+)
+
+// Mouse buttons can be swapped around with --mouse-buttons, eg; `right=middle` makes
+// the right button paste, for mice or terminals without a middle button to report.
+// Buttons mapped to `none` are ignored.
+var mouseButtonNames = map[string]tcell.ButtonMask{
+	"left":   tcell.Button1,
+	"middle": tcell.Button2,
+	"right":  tcell.Button3,
+	"none":   tcell.ButtonNone,
+}
+
+var mouseButtonMap map[tcell.ButtonMask]tcell.ButtonMask
+
+func setupMouseButtons(changes string) error {
+	mouseButtonMap = make(map[tcell.ButtonMask]tcell.ButtonMask)
+	for _, change := range strings.Split(changes, ",") {
+		if strings.TrimSpace(change) == "" {
+			continue
+		}
+		parts := strings.Split(change, "=")
+		if len(parts) != 2 {
+			return errors.New(fmt.Sprintf("'%s' should look like 'right=middle'", change))
+		}
+		from, isFromKnown := mouseButtonNames[strings.TrimSpace(parts[0])]
+		to, isToKnown := mouseButtonNames[strings.TrimSpace(parts[1])]
+		if !isFromKnown || !isToKnown || from == tcell.ButtonNone {
+			return errors.New(fmt.Sprintf(
+				"'%s' should map left, middle or right to one of them or none", change))
+		}
+		mouseButtonMap[from] = to
+	}
+	return nil
+}
+
+func remapMouseButtons(button tcell.ButtonMask) tcell.ButtonMask {
+	remapped := button &^ (tcell.Button1 | tcell.Button2 | tcell.Button3)
+	for _, original := range []tcell.ButtonMask{tcell.Button1, tcell.Button2, tcell.Button3} {
+		if button&original == 0 {
+			continue
+		}
+		if to, ok := mouseButtonMap[original]; ok {
+			remapped |= to
+		} else {
+			remapped |= original
+		}
+	}
+	return remapped
+}
+
+// Terminals don't let programs read their clipboard, so a middle click pastes what
+// Browsh itself last copied, like X's primary selection, into the input box clicked on.
+var isMiddleButtonDown = false
+
+// Returns whether the middle button was handled, rather than it being for the page
+func handleMiddleButton(button tcell.ButtonMask, xInFrame, yInFrame int) bool {
+	isDown := button&tcell.Button2 != 0
+	wasDown := isMiddleButtonDown
+	isMiddleButtonDown = isDown
+	if !isDown {
+		return wasDown
+	}
+	if !wasDown {
+		middleClick(xInFrame, yInFrame)
+	}
+	return true
+}
+
+func middleClick(xInFrame, yInFrame int) {
+	CurrentTab.frame.maybeFocusInputBox(xInFrame, yInFrame)
+	pasteIntoInputBox()
+}
+
+func pasteIntoInputBox() {
+	if activeInputBox == nil || isUIInputBoxActive() {
+		sendMessageToWebExtension("/status,Middle click an input box to paste into it")
+		return
+	}
+	if lastCopiedText == "" {
+		sendMessageToWebExtension("/status," + nothingCopiedMessage())
+		return
+	}
+	activeInputBox.removeSelectedText()
+	activeInputBox.cursorInsertText(lastCopiedText)
+	renderCurrentTabWindow()
+}
+
+func nothingCopiedMessage() string {
+	message := "Nothing has been copied yet"
+	if key := keyForAction("copy-mode"); key != "" {
+		message += ", " + key + " copies from the page"
+	}
+	return message
+}
+
+// For keyboards, or terminals that don't report the middle button at all
+func middleClickAtMousePointer() {
+	if !mousePointer.isVisible || isInUnfocusedPane(mousePointer.y) {
+		pasteIntoInputBox()
+		return
+	}
+	paneTop, _ := focusedPaneArea()
+	xScroll, yScroll := CurrentTab.frame.viewport.position()
+	middleClick(mousePointer.x+xScroll, mousePointer.y-paneTop+yScroll)
+}
@@ -0,0 +1,90 @@
+package browsh
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell"
+)
+
+// Terminals don't all draw the mouse pointer, and those that do often hide it while
+// typing, so Browsh marks the cell under it by swapping that cell's colours.
+var mousePointer struct {
+	isVisible bool
+	x, y      int
+}
+
+// Returns whether the pointer moved to a different cell
+func moveMousePointer(x, y int) bool {
+	if mousePointer.isVisible && mousePointer.x == x && mousePointer.y == y {
+		return false
+	}
+	mousePointer.isVisible = true
+	mousePointer.x = x
+	mousePointer.y = y
+	return true
+}
+
+func overlayMousePointer() {
+	if !mousePointer.isVisible {
+		return
+	}
+	top, rows := focusedPaneArea()
+	if mousePointer.y < top || mousePointer.y >= top+rows {
+		return
+	}
+	mainc, combc, style, _ := screen.GetContent(mousePointer.x, mousePointer.y)
+	_, _, attributes := style.Decompose()
+	isReversed := attributes&tcell.AttrReverse != 0
+	screen.SetContent(mousePointer.x, mousePointer.y, mainc, combc, style.Reverse(!isReversed))
+}
+
+// Terminals report every cell the mouse moves through, far more often than pages need
+// to know, and each one waits for the websocket. So movement, including dragging, is
+// only sent every mouseMotionInterval, with just the latest position. Presses and
+// releases are sent straight away, after any movement that's waiting, so that pages
+// see them in the right place.
+const mouseMotionInterval = 50 * time.Millisecond
+
+var mouseMotion struct {
+	sync.Mutex
+	lastButtons tcell.ButtonMask
+	pending     string
+	isScheduled bool
+}
+
+func sendMouseEvent(button tcell.ButtonMask, message string) {
+	mouseMotion.Lock()
+	defer mouseMotion.Unlock()
+	isMotion := button == mouseMotion.lastButtons
+	mouseMotion.lastButtons = button
+	if !isMotion {
+		flushMouseMotionLocked()
+		countStat(&stats.EventsInjected, 1)
+		sendMessageToWebExtension(message)
+		return
+	}
+	mouseMotion.pending = message
+	if !mouseMotion.isScheduled {
+		mouseMotion.isScheduled = true
+		time.AfterFunc(mouseMotionInterval, flushMouseMotion)
+	}
+}
+
+func flushMouseMotion() {
+	defer recoverAndShutdown()
+	mouseMotion.Lock()
+	defer mouseMotion.Unlock()
+	mouseMotion.isScheduled = false
+	flushMouseMotionLocked()
+}
+
+// Sent while locked, so that a press can't overtake the movement before it
+func flushMouseMotionLocked() {
+	if mouseMotion.pending == "" {
+		return
+	}
+	countStat(&stats.EventsInjected, 1)
+	sendMessageToWebExtension(mouseMotion.pending)
+	mouseMotion.pending = ""
+}
@@ -0,0 +1,299 @@
+package browsh
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Lets fleets of kiosks be managed centrally through an MQTT broker. Commands are
+// read from `<prefix>/command/<name>`, with any argument as the message's payload.
+// They are; `navigate` to a URL, `reload` the current tab, take a `screenshot`,
+// which is uploaded if --screenshot-upload-command is set, and `zoom` the current tab
+// by a factor like 1.5.
+//
+// Session events, the same as for webhooks, are published as JSON to
+// `<prefix>/status`, and `<prefix>/online` says whether Browsh is running.
+//
+// Only as much of MQTT 3.1.1 as that needs is implemented here: QoS 0, no TLS.
+type mqttConnection struct {
+	sync.Mutex
+	conn net.Conn
+}
+
+var mqttBrokerConnection = &mqttConnection{}
+
+const (
+	mqttConnect     = 0x10
+	mqttConnAck     = 0x20
+	mqttPublish     = 0x30
+	mqttSubscribe   = 0x82
+	mqttPingRequest = 0xC0
+	mqttDisconnect  = 0xE0
+	mqttKeepAlive   = 60
+)
+
+var mqttCommands = []string{"navigate", "reload", "screenshot", "zoom"}
+
+func mqttTopicName(name string) string {
+	return *mqttTopic + "/" + name
+}
+
+// Keep reconnecting, a kiosk may well outlive its broker
+func startMQTT() {
+	defer recoverAndShutdown()
+	broker, _ := url.Parse(*mqttBroker)
+	for {
+		conn, err := connectToMQTTBroker(broker)
+		if err != nil {
+			logError("MQTT: " + err.Error())
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		logInfo("MQTT: connected to " + broker.Host)
+		mqttBrokerConnection.Lock()
+		mqttBrokerConnection.conn = conn
+		mqttBrokerConnection.Unlock()
+		publishMQTT(mqttTopicName("online"), []byte("true"), true)
+		go pingMQTTBroker(conn)
+		err = readFromMQTTBroker(conn)
+		mqttBrokerConnection.Lock()
+		mqttBrokerConnection.conn = nil
+		mqttBrokerConnection.Unlock()
+		conn.Close()
+		logError("MQTT: disconnected: " + err.Error())
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func connectToMQTTBroker(broker *url.URL) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", broker.Host, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	conn.Write(buildMQTTConnect(broker.User))
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	header, body, err := readMQTTPacket(conn)
+	conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if header != mqttConnAck || len(body) != 2 || body[1] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("broker refused the connection (%v)", body)
+	}
+	subscribe := append([]byte{0, 1}, mqttString(mqttTopicName("command/+"))...)
+	conn.Write(mqttPacket(mqttSubscribe, append(subscribe, 0)))
+	return conn, nil
+}
+
+// Clean session, with a will that marks Browsh as offline should it disappear
+// without saying goodbye.
+func buildMQTTConnect(user *url.Userinfo) []byte {
+	flags := byte(0x02 | 0x04 | 0x20)
+	body := append(mqttString("MQTT"), 4, 0, 0, 0)
+	binary.BigEndian.PutUint16(body[len(body)-2:], mqttKeepAlive)
+	hostname, _ := os.Hostname()
+	body = append(body, mqttString(fmt.Sprintf("browsh-%s-%d", hostname, os.Getpid()))...)
+	body = append(body, mqttString(mqttTopicName("online"))...)
+	body = append(body, mqttString("false")...)
+	if user != nil {
+		flags |= 0x80
+		body = append(body, mqttString(user.Username())...)
+		if password, ok := user.Password(); ok {
+			flags |= 0x40
+			body = append(body, mqttString(password)...)
+		}
+	}
+	body[7] = flags
+	return mqttPacket(mqttConnect, body)
+}
+
+func pingMQTTBroker(conn net.Conn) {
+	defer recoverAndShutdown()
+	for {
+		time.Sleep(mqttKeepAlive / 2 * time.Second)
+		mqttBrokerConnection.Lock()
+		isCurrent := mqttBrokerConnection.conn == conn
+		if isCurrent {
+			conn.Write(mqttPacket(mqttPingRequest, nil))
+		}
+		mqttBrokerConnection.Unlock()
+		if !isCurrent {
+			return
+		}
+	}
+}
+
+// The broker answers every ping, so a connection that's silent for longer than the
+// keep alive has died without saying so, like when a NAT forgets about it
+func readFromMQTTBroker(conn net.Conn) error {
+	for {
+		conn.SetReadDeadline(time.Now().Add(1.5 * mqttKeepAlive * time.Second))
+		header, body, err := readMQTTPacket(conn)
+		if err != nil {
+			return err
+		}
+		if header&0xF0 != mqttPublish {
+			continue
+		}
+		topic, payload, err := parseMQTTPublish(header, body)
+		if err != nil {
+			return err
+		}
+		command := strings.TrimPrefix(topic, mqttTopicName("command/"))
+		message, err := mqttCommandToMessage(command, string(payload))
+		if err != nil {
+			logError("MQTT: " + err.Error())
+			continue
+		}
+		if (command == "zoom" || command == "reload") && !isWebextFeatureSupported(command) {
+			logError("MQTT: the webextension is too old for " + command)
+			continue
+		}
+		Log("MQTT: received " + command)
+		sendMessageToWebExtension(message)
+	}
+}
+
+func mqttCommandToMessage(command, payload string) (string, error) {
+	payload = strings.TrimSpace(payload)
+	switch command {
+	case "navigate":
+		if payload == "" {
+			return "", errors.New("navigate needs a URL")
+		}
+		return "/url_bar," + payload, nil
+	case "reload":
+		return "/reload", nil
+	case "screenshot":
+		return "/screenshot", nil
+	case "zoom":
+		factor, err := strconv.ParseFloat(payload, 64)
+		// The range that Firefox allows
+		if err != nil || factor < 0.3 || factor > 3 {
+			return "", fmt.Errorf("zoom '%s' isn't a number between 0.3 and 3", payload)
+		}
+		return fmt.Sprintf("/zoom,%g", factor), nil
+	}
+	return "", fmt.Errorf("unknown command '%s', choose from: %s",
+		command, strings.Join(mqttCommands, ", "))
+}
+
+func publishMQTTEvent(event string, t *tab) {
+	if *mqttBroker == "" {
+		return
+	}
+	publishMQTTPayload(newWebhookPayload(event, t))
+	if event == "quit" {
+		publishMQTT(mqttTopicName("online"), []byte("false"), true)
+		mqttBrokerConnection.Lock()
+		if mqttBrokerConnection.conn != nil {
+			mqttBrokerConnection.conn.Write(mqttPacket(mqttDisconnect, nil))
+		}
+		mqttBrokerConnection.Unlock()
+	}
+}
+
+func publishMQTTPayload(payload webhookPayload) {
+	if *mqttBroker == "" {
+		return
+	}
+	marshalled, _ := json.Marshal(payload)
+	publishMQTT(mqttTopicName("status"), marshalled, false)
+}
+
+func publishMQTT(topic string, payload []byte, isRetained bool) {
+	mqttBrokerConnection.Lock()
+	defer mqttBrokerConnection.Unlock()
+	if mqttBrokerConnection.conn == nil {
+		return
+	}
+	header := byte(mqttPublish)
+	if isRetained {
+		header |= 0x01
+	}
+	packet := mqttPacket(header, append(mqttString(topic), payload...))
+	if _, err := mqttBrokerConnection.conn.Write(packet); err != nil {
+		logError("MQTT: " + err.Error())
+	}
+}
+
+func mqttString(text string) []byte {
+	encoded := make([]byte, 2, len(text)+2)
+	binary.BigEndian.PutUint16(encoded, uint16(len(text)))
+	return append(encoded, text...)
+}
+
+// The length of the rest of the packet is sent 7 bits at a time, the top bit of
+// each byte saying whether there's more to come.
+func mqttPacket(header byte, body []byte) []byte {
+	packet := []byte{header}
+	length := len(body)
+	for {
+		digit := byte(length % 128)
+		length /= 128
+		if length > 0 {
+			digit |= 0x80
+		}
+		packet = append(packet, digit)
+		if length == 0 {
+			break
+		}
+	}
+	return append(packet, body...)
+}
+
+func readMQTTPacket(reader io.Reader) (byte, []byte, error) {
+	var buffer [1]byte
+	if _, err := io.ReadFull(reader, buffer[:]); err != nil {
+		return 0, nil, err
+	}
+	header := buffer[0]
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed packet length")
+		}
+		if _, err := io.ReadFull(reader, buffer[:]); err != nil {
+			return 0, nil, err
+		}
+		length += int(buffer[0]&0x7F) * multiplier
+		multiplier *= 128
+		if buffer[0]&0x80 == 0 {
+			break
+		}
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+// Commands are subscribed to at QoS 0, but skip the packet ID in case a broker
+// sends one anyway.
+func parseMQTTPublish(header byte, body []byte) (string, []byte, error) {
+	if len(body) < 2 {
+		return "", nil, errors.New("malformed publish packet")
+	}
+	topicLength := int(binary.BigEndian.Uint16(body))
+	start := 2 + topicLength
+	if header&0x06 != 0 {
+		start += 2
+	}
+	if start > len(body) {
+		return "", nil, errors.New("malformed publish packet")
+	}
+	return string(body[2 : 2+topicLength]), body[start:], nil
+}
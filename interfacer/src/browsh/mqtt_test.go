@@ -0,0 +1,66 @@
+package browsh
+
+import (
+	"bytes"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestMQTT(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "MQTT tests")
+}
+
+var _ = Describe("MQTT", func() {
+	It("should round trip packets longer than a single length byte", func() {
+		payload := strings.Repeat("x", 300)
+		packet := mqttPacket(mqttPublish, append(mqttString("browsh/status"), payload...))
+		Expect(packet[1:3]).To(Equal([]byte{0xBB, 0x02}))
+		header, body, err := readMQTTPacket(bytes.NewReader(packet))
+		Expect(err).NotTo(HaveOccurred())
+		topic, received, err := parseMQTTPublish(header, body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(topic).To(Equal("browsh/status"))
+		Expect(string(received)).To(Equal(payload))
+	})
+
+	It("should connect and subscribe to commands", func() {
+		listener, _ := net.Listen("tcp", "127.0.0.1:0")
+		defer listener.Close()
+		packets := make(chan []byte, 2)
+		go func() {
+			conn, _ := listener.Accept()
+			defer conn.Close()
+			_, connect, _ := readMQTTPacket(conn)
+			packets <- connect
+			conn.Write([]byte{mqttConnAck, 2, 0, 0})
+			_, subscribe, _ := readMQTTPacket(conn)
+			packets <- subscribe
+		}()
+		broker, _ := url.Parse("tcp://kiosk:secret@" + listener.Addr().String())
+		conn, err := connectToMQTTBroker(broker)
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		var connect, subscribe []byte
+		Eventually(packets).Should(Receive(&connect))
+		Expect(connect[:6]).To(Equal(mqttString("MQTT")))
+		Expect(connect[7]).To(Equal(byte(0xE6)))
+		Expect(string(connect)).To(HaveSuffix("\x00\x05kiosk\x00\x06secret"))
+		Eventually(packets).Should(Receive(&subscribe))
+		Expect(subscribe).To(Equal(append(append([]byte{0, 1}, mqttString("browsh/command/+")...), 0)))
+	})
+
+	It("should turn commands into messages for the browser", func() {
+		Expect(mqttCommandToMessage("navigate", " https://www.brow.sh\n")).To(Equal("/url_bar,https://www.brow.sh"))
+		Expect(mqttCommandToMessage("zoom", "1.5")).To(Equal("/zoom,1.5"))
+		_, err := mqttCommandToMessage("zoom", "10")
+		Expect(err).To(HaveOccurred())
+		_, err = mqttCommandToMessage("launch", "")
+		Expect(err).To(MatchError(ContainSubstring("unknown command 'launch'")))
+	})
+})
@@ -0,0 +1,131 @@
+package browsh
+
+import (
+	"fmt"
+
+	"github.com/gdamore/tcell"
+)
+
+// The TTY can be split into two panes, one above the other, each showing its own tab
+// with its own scroll position. The focused pane always shows the current tab. As only
+// the current tab is active in the browser, the other pane shows whatever its tab last
+// sent, which is handy for keeping an eye on one page whilst using another.
+var (
+	isSplit bool
+	// The tab shown in the pane that doesn't have focus
+	splitTab *tab
+	// Whether the bottom pane, rather than the top pane, has focus
+	isBottomPaneFocused bool
+)
+
+// Returns the first TTY row of a pane and how many rows it has. When split, the
+// panes share the space below the tabs and URL bar, with a single row between them
+// showing the title of the tab in the unfocused pane.
+func paneArea(isBottom bool) (int, int) {
+	_, height := screen.Size()
+	available := height - uiHeight
+	if !isSplit {
+		return uiHeight, available
+	}
+	topRows := available / 2
+	if !isBottom {
+		return uiHeight, topRows
+	}
+	return uiHeight + topRows + 1, available - topRows - 1
+}
+
+func focusedPaneArea() (int, int) {
+	return paneArea(isBottomPaneFocused)
+}
+
+func unfocusedPaneArea() (int, int) {
+	return paneArea(!isBottomPaneFocused)
+}
+
+func toggleSplit() {
+	if isSplit {
+		unsplit()
+	} else {
+		split()
+	}
+	renderUI()
+	renderCurrentTabWindow()
+}
+
+func split() {
+	if len(tabsOrder) < 2 {
+		sendMessageToWebExtension("/status,Open another tab to split the screen")
+		return
+	}
+	isSplit = true
+	isBottomPaneFocused = false
+	splitTab = nil
+	for _, tabID := range tabsOrder {
+		if tabID != CurrentTab.ID {
+			splitTab = Tabs[tabID]
+			break
+		}
+	}
+}
+
+func unsplit() {
+	isSplit = false
+	isBottomPaneFocused = false
+	splitTab = nil
+	screen.Clear()
+}
+
+// Moving focus to the other pane makes its tab the current tab, both here and in the
+// browser.
+func switchPaneFocus() {
+	if !isSplit {
+		return
+	}
+	CurrentTab, splitTab = splitTab, CurrentTab
+	isBottomPaneFocused = !isBottomPaneFocused
+	sendMessageToWebExtension(fmt.Sprintf("/switch_to_tab,%d", CurrentTab.ID))
+	renderUI()
+	renderCurrentTabWindow()
+}
+
+// Both panes showing the same tab isn't useful, so when the current tab changes to the
+// tab in the other pane, that pane takes the previously current tab instead.
+func keepSplitTabDistinct(previousTab *tab) {
+	if isSplit && CurrentTab == splitTab {
+		splitTab = previousTab
+	}
+}
+
+// Called after a tab is removed, as it may have been in the unfocused pane
+func maybeUnsplitAfterTabRemoval() {
+	if !isSplit {
+		return
+	}
+	if splitTab == nil || !isTabPresent(splitTab.ID) || splitTab == CurrentTab {
+		unsplit()
+	}
+}
+
+func isInUnfocusedPane(y int) bool {
+	if !isSplit {
+		return false
+	}
+	top, rows := unfocusedPaneArea()
+	return y >= top && y < top+rows
+}
+
+func renderSplitSeparator() {
+	width, _ := screen.Size()
+	top, rows := paneArea(false)
+	y := top + rows
+	title := []rune(" " + splitTab.Title + " ")
+	for x := 0; x < width; x++ {
+		character := '─'
+		style := tcell.StyleDefault
+		if x > 0 && x-1 < len(title) {
+			character = title[x-1]
+			style = style.Reverse(true)
+		}
+		screen.SetContent(x, y, character, nil, style)
+	}
+}
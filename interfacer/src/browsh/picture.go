@@ -0,0 +1,110 @@
+package browsh
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/gdamore/tcell"
+)
+
+// Brightness, contrast and gamma adjust the page's colours as they arrive from the
+// browser, before they're dithered or fitted to the terminal's palette. Dark pages
+// often use greys too close together to tell apart once a terminal has rounded them
+// to its palette, and lifting them first keeps them apart.
+type picture struct {
+	sync.Mutex
+	brightness int
+	contrast   float64
+	gamma      float64
+	// Every channel of every colour goes through the same curve, so it's only
+	// worked out once for each of the 256 values.
+	curve        [256]int32
+	isAdjusted   bool
+	isCurveBuilt bool
+}
+
+var pictureSettings picture
+
+const (
+	brightnessStep = 10
+	contrastStep   = 0.1
+	gammaStep      = 0.1
+)
+
+func setupPicture() {
+	pictureSettings.Lock()
+	defer pictureSettings.Unlock()
+	pictureSettings.brightness = *brightness
+	pictureSettings.contrast = *contrast
+	pictureSettings.gamma = *gamma
+	pictureSettings.buildCurve()
+}
+
+func (p *picture) buildCurve() {
+	p.isAdjusted = p.brightness != 0 || p.contrast != 1 || p.gamma != 1
+	for i := range p.curve {
+		value := float64(i) / 255
+		value = (value-0.5)*p.contrast + 0.5
+		value += float64(p.brightness) / 100
+		value = math.Max(0, math.Min(1, value))
+		value = math.Pow(value, 1/p.gamma)
+		p.curve[i] = int32(math.Round(value * 255))
+	}
+	p.isCurveBuilt = true
+}
+
+// Adjusts a flat list of RGB values, as they come from the browser. The original
+// list is left alone.
+func adjustPicture(data []int32) []int32 {
+	pictureSettings.Lock()
+	defer pictureSettings.Unlock()
+	if !pictureSettings.isCurveBuilt || !pictureSettings.isAdjusted {
+		return data
+	}
+	adjusted := make([]int32, len(data))
+	for i, value := range data {
+		adjusted[i] = pictureSettings.curve[clampChannel(value)]
+	}
+	return adjusted
+}
+
+func adjustedRGBColour(r, g, b int32) tcell.Color {
+	pictureSettings.Lock()
+	defer pictureSettings.Unlock()
+	if pictureSettings.isCurveBuilt && pictureSettings.isAdjusted {
+		curve := &pictureSettings.curve
+		r, g, b = curve[clampChannel(r)], curve[clampChannel(g)], curve[clampChannel(b)]
+	}
+	return tcell.NewRGBColor(r, g, b)
+}
+
+func changePicture(action string) {
+	pictureSettings.Lock()
+	switch action {
+	case "brightness-up":
+		pictureSettings.brightness = clamp(pictureSettings.brightness+brightnessStep, -100, 100)
+	case "brightness-down":
+		pictureSettings.brightness = clamp(pictureSettings.brightness-brightnessStep, -100, 100)
+	case "contrast-up":
+		pictureSettings.contrast = math.Min(pictureSettings.contrast+contrastStep, 5)
+	case "contrast-down":
+		pictureSettings.contrast = math.Max(pictureSettings.contrast-contrastStep, contrastStep)
+	case "gamma-up":
+		pictureSettings.gamma = math.Min(pictureSettings.gamma+gammaStep, 5)
+	case "gamma-down":
+		pictureSettings.gamma = math.Max(pictureSettings.gamma-gammaStep, gammaStep)
+	case "picture-reset":
+		pictureSettings.brightness, pictureSettings.contrast, pictureSettings.gamma = 0, 1, 1
+	}
+	// Repeatedly adding tenths drifts, which would stop 1 from meaning no change
+	pictureSettings.contrast = math.Round(pictureSettings.contrast*10) / 10
+	pictureSettings.gamma = math.Round(pictureSettings.gamma*10) / 10
+	pictureSettings.buildCurve()
+	message := fmt.Sprintf("Brightness %d, contrast %.1f, gamma %.1f",
+		pictureSettings.brightness, pictureSettings.contrast, pictureSettings.gamma)
+	pictureSettings.Unlock()
+	sendMessageToWebExtension("/status," + message)
+	// The frames already in the TTY were adjusted with the old settings
+	sendMessageToWebExtension("/tab_command,/rebuild_text")
+}
@@ -0,0 +1,196 @@
+package browsh
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell"
+)
+
+// A prompt asks the user for a single line of text on the status line at the bottom
+// of the TTY. Whilst it's open all key presses go to it, rather than the browser.
+var (
+	promptInputBox = inputBox{
+		X:        0,
+		Height:   1,
+		text:     "",
+		FgColour: [3]int32{255, 255, 255},
+		bgColour: [3]int32{-1, -1, -1},
+	}
+	promptLabel    string
+	promptCallback func(text string)
+	// Optionally completes the prompt's text when TAB is pressed
+	promptCompleter func(text string) string
+	// Optionally called when the prompt is closed with ESC
+	promptCanceller func()
+	// Prompts the browser asks for can arrive whilst another is still open, so they
+	// wait here until it's closed.
+	queuedPrompts []func()
+)
+
+// The URL bar and prompts are part of Browsh's own UI, so unlike the input boxes on a
+// page they aren't offset by the frame's scroll and aren't synced to the browser.
+func isUIInputBoxActive() bool {
+	return urlInputBox.isActive || promptInputBox.isActive
+}
+
+func openPrompt(label, initialText string, callback func(text string)) {
+	if urlInputBox.isActive {
+		urlBarFocus(false)
+	}
+	width, height := screen.Size()
+	promptLabel = label
+	promptCallback = callback
+	promptInputBox.X = utf8.RuneCountInString(label)
+	promptInputBox.Y = height - 1
+	promptInputBox.Width = width - promptInputBox.X
+	promptInputBox.text = initialText
+	promptInputBox.selectionOff()
+	putPromptCursorAtEnd()
+	promptInputBox.isActive = true
+	activeInputBox = &promptInputBox
+	renderPrompt()
+}
+
+// Opens a prompt now, or once the open prompt has been closed
+func queuePrompt(open func()) {
+	if promptInputBox.isActive {
+		queuedPrompts = append(queuedPrompts, open)
+		return
+	}
+	open()
+}
+
+// Called after a prompt's callback, which may well have opened a prompt of its own
+func openQueuedPrompt() {
+	if promptInputBox.isActive || len(queuedPrompts) == 0 {
+		return
+	}
+	open := queuedPrompts[0]
+	queuedPrompts = queuedPrompts[1:]
+	open()
+}
+
+func closePrompt() {
+	activeInputBox = nil
+	promptInputBox.isActive = false
+	promptCallback = nil
+	promptCompleter = nil
+	promptCanceller = nil
+	promptInputBox.Type = ""
+	renderCurrentTabWindow()
+}
+
+func submitPrompt() {
+	callback := promptCallback
+	text := promptInputBox.text
+	closePrompt()
+	if callback != nil {
+		callback(text)
+	}
+	openQueuedPrompt()
+}
+
+func renderPrompt() {
+	_, height := screen.Size()
+	writeString(0, height-1, promptLabel, tcell.StyleDefault)
+	promptInputBox.renderURLBox()
+	fillLineToEnd(promptInputBox.X+len(promptInputBox.textToDisplay()), height-1)
+}
+
+// Keeps the end of the text in view, even when it's longer than the prompt
+func putPromptCursorAtEnd() {
+	promptInputBox.putCursorAtEnd()
+	promptInputBox.xScroll = 0
+	if promptInputBox.textCursor >= promptInputBox.Width {
+		promptInputBox.xScroll = promptInputBox.textCursor - promptInputBox.Width + 1
+	}
+}
+
+func handlePromptKeyPress(ev *tcell.EventKey) {
+	if keyMap[keyChordFromEvent(ev)] == "quit" {
+		// In kiosk mode quitting is only ever done with the passphrase
+		if !*isKioskMode {
+			quitBrowsh()
+		}
+		return
+	}
+	switch ev.Key() {
+	case tcell.KeyEscape:
+		canceller := promptCanceller
+		closePrompt()
+		if canceller != nil {
+			canceller()
+		}
+		openQueuedPrompt()
+	case tcell.KeyTab:
+		if promptCompleter != nil {
+			promptInputBox.text = promptCompleter(promptInputBox.text)
+			putPromptCursorAtEnd()
+			renderPrompt()
+		}
+	default:
+		handleInputBoxInput(ev)
+	}
+}
+
+// Like openPrompt, but the text is hidden as it's typed
+func openPasswordPrompt(label string, callback func(text string)) {
+	openPrompt(label, "", callback)
+	promptInputBox.Type = "password"
+	renderPrompt()
+}
+
+// Searching for text in the page is done by the browser, which selects the match. But
+// it's the TTY that decides what part of the page is shown, so the match's row is sent
+// back for the TTY to scroll to.
+var lastFindQuery string
+
+func openFind() {
+	openPrompt("Find: ", lastFindQuery, func(text string) {
+		lastFindQuery = text
+		if text != "" {
+			sendFind(false)
+		}
+	})
+}
+
+func findAgain(isBackwards bool) {
+	if lastFindQuery == "" {
+		openFind()
+		return
+	}
+	sendFind(isBackwards)
+}
+
+func sendFind(isBackwards bool) {
+	if !requireWebextFeature("find") {
+		return
+	}
+	marshalled, _ := json.Marshal(map[string]interface{}{
+		"query":     lastFindQuery,
+		"backwards": isBackwards,
+	})
+	sendMessageToWebExtension("/tab_command,/find," + string(marshalled))
+}
+
+// Put the match a third of the way down, so that there's some context above it
+func handleFindResult(row string) {
+	if CurrentTab == nil {
+		return
+	}
+	_, yScroll := CurrentTab.frame.viewport.position()
+	_, height := focusedPaneArea()
+	scrollFrame(toInt(row) - yScroll - height/3)
+}
+
+func openLinkSearch() {
+	if !requireWebextFeature("follow_link") {
+		return
+	}
+	openPrompt("Follow link: ", "", func(text string) {
+		if text != "" {
+			sendMessageToWebExtension("/tab_command,/follow_link," + text)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package browsh
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestPrompt(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Prompt tests")
+}
+
+var _ = Describe("Prompt", func() {
+	BeforeEach(func() {
+		newTab(1)
+		CurrentTab = Tabs[1]
+		simScreen := tcell.NewSimulationScreen("UTF-8")
+		simScreen.Init()
+		simScreen.SetSize(80, 5)
+		screen = simScreen
+	})
+
+	AfterEach(func() {
+		closePrompt()
+		queuedPrompts = nil
+		pendingAuthRequests = nil
+		CurrentTab = nil
+		screen = nil
+	})
+
+	It("should wait for the open prompt to close before asking to log in", func() {
+		var found string
+		openPrompt("Find: ", "", func(text string) { found = text })
+		handleAuthRequest(`{"id": "1", "host": "example.com"}`)
+		Expect(promptLabel).To(Equal("Find: "))
+		promptInputBox.text = "needle"
+		submitPrompt()
+		Expect(found).To(Equal("needle"))
+		Expect(promptLabel).To(Equal("Log in to example.com. Username: "))
+	})
+
+	It("should open the queued prompt when the open one is cancelled", func() {
+		openPrompt("Find: ", "", nil)
+		handleAuthRequest(`{"id": "1", "host": "example.com"}`)
+		handlePromptKeyPress(tcell.NewEventKey(tcell.KeyEscape, 0, tcell.ModNone))
+		Expect(promptLabel).To(Equal("Log in to example.com. Username: "))
+	})
+})
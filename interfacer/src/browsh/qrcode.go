@@ -0,0 +1,509 @@
+package browsh
+
+import (
+	"errors"
+
+	"github.com/gdamore/tcell"
+)
+
+// A minimal QR code encoder, just enough to share URLs. It only supports byte mode,
+// the lowest level of error correction and versions 1 to 10, which is room for 271
+// bytes. The lowest error correction is fine here as the code is only ever read off a
+// screen, never from a crumpled bit of paper.
+//
+// See https://www.thonky.com/qr-code-tutorial/ for a good walkthrough of the spec.
+
+type qrVersion struct {
+	// Total number of codewords, both data and error correction
+	totalCodewords int
+	// Error correction codewords in each block
+	eccPerBlock int
+	blocks      int
+	// Centres of the alignment patterns, in both directions
+	alignments []int
+}
+
+// Error correction level L
+var qrVersions = []qrVersion{
+	{},
+	{26, 7, 1, nil},
+	{44, 10, 1, []int{6, 18}},
+	{70, 15, 1, []int{6, 22}},
+	{100, 20, 1, []int{6, 26}},
+	{134, 26, 1, []int{6, 30}},
+	{172, 18, 2, []int{6, 34}},
+	{196, 20, 2, []int{6, 22, 38}},
+	{242, 24, 2, []int{6, 24, 42}},
+	{292, 30, 2, []int{6, 26, 46}},
+	{346, 18, 4, []int{6, 28, 50}},
+}
+
+// A QR code's modules, true is dark, indexed by [y][x]
+type qrCode [][]bool
+
+type qrBuilder struct {
+	version    int
+	size       int
+	modules    qrCode
+	isFunction [][]bool
+}
+
+func (v qrVersion) dataCodewords() int {
+	return v.totalCodewords - v.eccPerBlock*v.blocks
+}
+
+// Encode text into the smallest QR code that will fit it
+func encodeQRCode(text string) (qrCode, error) {
+	data := []byte(text)
+	for version := 1; version < len(qrVersions); version++ {
+		codewords, ok := qrDataCodewords(data, version)
+		if !ok {
+			continue
+		}
+		return buildQRCode(version, qrAddErrorCorrection(codewords, version)), nil
+	}
+	return nil, errors.New("Text is too long for a QR code")
+}
+
+// Byte mode indicator, the length and the data itself, then padded to fill the version
+func qrDataCodewords(data []byte, version int) ([]byte, bool) {
+	var bits []bool
+	appendBits := func(value, length int) {
+		for i := length - 1; i >= 0; i-- {
+			bits = append(bits, (value>>uint(i))&1 == 1)
+		}
+	}
+	lengthBits := 8
+	if version >= 10 {
+		lengthBits = 16
+	}
+	capacity := qrVersions[version].dataCodewords() * 8
+	if 4+lengthBits+len(data)*8 > capacity {
+		return nil, false
+	}
+	appendBits(4, 4)
+	appendBits(len(data), lengthBits)
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+	for i := 0; i < 4 && len(bits) < capacity; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+	for pad := 0xEC; len(bits) < capacity; pad ^= 0xEC ^ 0x11 {
+		appendBits(pad, 8)
+	}
+	codewords := make([]byte, len(bits)/8)
+	for i, bit := range bits {
+		if bit {
+			codewords[i/8] |= 1 << uint(7-i%8)
+		}
+	}
+	return codewords, true
+}
+
+// Split the data into blocks, add the error correction to each one and then interleave
+// them all together. When the blocks aren't all the same size, the first ones are a
+// codeword shorter.
+func qrAddErrorCorrection(data []byte, version int) []byte {
+	v := qrVersions[version]
+	shortBlocks := v.blocks - v.totalCodewords%v.blocks
+	shortBlockDataLength := v.totalCodewords/v.blocks - v.eccPerBlock
+	divisor := qrReedSolomonDivisor(v.eccPerBlock)
+	var dataBlocks, eccBlocks [][]byte
+	offset := 0
+	for i := 0; i < v.blocks; i++ {
+		length := shortBlockDataLength
+		if i >= shortBlocks {
+			length++
+		}
+		block := data[offset : offset+length]
+		offset += length
+		dataBlocks = append(dataBlocks, block)
+		eccBlocks = append(eccBlocks, qrReedSolomonRemainder(block, divisor))
+	}
+	var result []byte
+	for i := 0; i <= shortBlockDataLength; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < v.eccPerBlock; i++ {
+		for _, block := range eccBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// Multiplication in GF(2^8) modulo the QR code's polynomial, x^8 + x^4 + x^3 + x^2 + 1
+func qrGaloisMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+func qrReedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	var root byte = 1
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			result[j] = qrGaloisMultiply(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = qrGaloisMultiply(root, 0x02)
+	}
+	return result
+}
+
+func qrReedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i := range result {
+			result[i] ^= qrGaloisMultiply(divisor[i], factor)
+		}
+	}
+	return result
+}
+
+func buildQRCode(version int, codewords []byte) qrCode {
+	size := version*4 + 17
+	b := &qrBuilder{version: version, size: size}
+	b.modules = make(qrCode, size)
+	b.isFunction = make([][]bool, size)
+	for y := 0; y < size; y++ {
+		b.modules[y] = make([]bool, size)
+		b.isFunction[y] = make([]bool, size)
+	}
+	b.drawFunctionPatterns()
+	b.drawCodewords(codewords)
+	bestMask, lowestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		b.applyMask(mask)
+		b.drawFormatBits(mask)
+		if penalty := b.penalty(); lowestPenalty < 0 || penalty < lowestPenalty {
+			bestMask, lowestPenalty = mask, penalty
+		}
+		// Masks are undone by applying them again
+		b.applyMask(mask)
+	}
+	b.applyMask(bestMask)
+	b.drawFormatBits(bestMask)
+	return b.modules
+}
+
+func (b *qrBuilder) set(x, y int, isDark bool) {
+	b.modules[y][x] = isDark
+	b.isFunction[y][x] = true
+}
+
+func (b *qrBuilder) drawFunctionPatterns() {
+	for i := 0; i < b.size; i++ {
+		b.set(6, i, i%2 == 0)
+		b.set(i, 6, i%2 == 0)
+	}
+	b.drawFinderPattern(3, 3)
+	b.drawFinderPattern(b.size-4, 3)
+	b.drawFinderPattern(3, b.size-4)
+	alignments := qrVersions[b.version].alignments
+	last := len(alignments) - 1
+	for i, x := range alignments {
+		for j, y := range alignments {
+			isOverFinder := (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0)
+			if !isOverFinder {
+				b.drawAlignmentPattern(x, y)
+			}
+		}
+	}
+	// Reserve the format areas, they're drawn properly once the mask is chosen
+	b.drawFormatBits(0)
+	b.drawVersionBits()
+}
+
+// Includes the light separator around the finder itself
+func (b *qrBuilder) drawFinderPattern(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			distance := qrMax(qrAbs(dx), qrAbs(dy))
+			if x+dx >= 0 && x+dx < b.size && y+dy >= 0 && y+dy < b.size {
+				b.set(x+dx, y+dy, distance != 2 && distance != 4)
+			}
+		}
+	}
+}
+
+func (b *qrBuilder) drawAlignmentPattern(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			b.set(x+dx, y+dy, qrMax(qrAbs(dx), qrAbs(dy)) != 1)
+		}
+	}
+}
+
+// 2 bits of error correction level (01 is L), 3 bits of mask and a 10 bit BCH code,
+// drawn twice.
+func (b *qrBuilder) drawFormatBits(mask int) {
+	data := 1<<3 | mask
+	remainder := data
+	for i := 0; i < 10; i++ {
+		remainder = (remainder << 1) ^ ((remainder >> 9) * 0x537)
+	}
+	bits := (data<<10 | remainder) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 == 1 }
+	for i := 0; i <= 5; i++ {
+		b.set(8, i, bit(i))
+	}
+	b.set(8, 7, bit(6))
+	b.set(8, 8, bit(7))
+	b.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		b.set(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		b.set(b.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		b.set(8, b.size-15+i, bit(i))
+	}
+	b.set(8, b.size-8, true)
+}
+
+// Only versions 7 and above include their version number, with a 12 bit BCH code
+func (b *qrBuilder) drawVersionBits() {
+	if b.version < 7 {
+		return
+	}
+	remainder := b.version
+	for i := 0; i < 12; i++ {
+		remainder = (remainder << 1) ^ ((remainder >> 11) * 0x1F25)
+	}
+	bits := b.version<<12 | remainder
+	for i := 0; i < 18; i++ {
+		isDark := (bits>>uint(i))&1 == 1
+		x := b.size - 11 + i%3
+		y := i / 3
+		b.set(x, y, isDark)
+		b.set(y, x, isDark)
+	}
+}
+
+// Data goes in a zigzag, 2 columns at a time, from the bottom right corner, skipping
+// over the vertical timing pattern.
+func (b *qrBuilder) drawCodewords(codewords []byte) {
+	i := 0
+	for right := b.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vertical := 0; vertical < b.size; vertical++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vertical
+				if (right+1)&2 == 0 {
+					y = b.size - 1 - vertical
+				}
+				if !b.isFunction[y][x] && i < len(codewords)*8 {
+					b.modules[y][x] = (codewords[i/8]>>uint(7-i%8))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (b *qrBuilder) applyMask(mask int) {
+	for y := 0; y < b.size; y++ {
+		for x := 0; x < b.size; x++ {
+			if !b.isFunction[y][x] && qrMaskBit(mask, x, y) {
+				b.modules[y][x] = !b.modules[y][x]
+			}
+		}
+	}
+}
+
+func qrMaskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// The spec's penalty rules for choosing a mask: long runs of the same colour, 2x2
+// blocks of the same colour, patterns that look like finders and an imbalance of dark
+// and light.
+func (b *qrBuilder) penalty() int {
+	penalty := 0
+	dark := 0
+	at := func(x, y int, isRow bool) bool {
+		if isRow {
+			return b.modules[y][x]
+		}
+		return b.modules[x][y]
+	}
+	for _, isRow := range []bool{true, false} {
+		for y := 0; y < b.size; y++ {
+			run := 1
+			for x := 1; x < b.size; x++ {
+				if at(x, y, isRow) == at(x-1, y, isRow) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					penalty += run - 2
+				}
+				run = 1
+			}
+			if run >= 5 {
+				penalty += run - 2
+			}
+			for x := 0; x+6 < b.size; x++ {
+				if b.isFinderLike(x, y, isRow, at) {
+					penalty += 40
+				}
+			}
+		}
+	}
+	for y := 0; y < b.size; y++ {
+		for x := 0; x < b.size; x++ {
+			if b.modules[y][x] {
+				dark++
+			}
+			if x+1 < b.size && y+1 < b.size {
+				colour := b.modules[y][x]
+				if colour == b.modules[y][x+1] &&
+					colour == b.modules[y+1][x] &&
+					colour == b.modules[y+1][x+1] {
+					penalty += 3
+				}
+			}
+		}
+	}
+	total := b.size * b.size
+	deviation := qrAbs(dark*20-total*10) / total
+	return penalty + deviation*10
+}
+
+// Dark, light, dark x3, light, dark, with 4 light modules (or the edge) on either side
+func (b *qrBuilder) isFinderLike(x, y int, isRow bool, at func(x, y int, isRow bool) bool) bool {
+	pattern := []bool{true, false, true, true, true, false, true}
+	for i, isDark := range pattern {
+		if at(x+i, y, isRow) != isDark {
+			return false
+		}
+	}
+	isLight := func(from, to int) bool {
+		for i := from; i < to; i++ {
+			if i >= 0 && i < b.size && at(i, y, isRow) {
+				return false
+			}
+		}
+		return true
+	}
+	return isLight(x-4, x) || isLight(x+7, x+11)
+}
+
+func qrAbs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func qrMax(x, y int) int {
+	if x > y {
+		return x
+	}
+	return y
+}
+
+// Number of light modules around the code, so that phones can find its edges. The QR
+// spec asks for 4, and some scanners fail with less.
+const qrQuietZone = 4
+
+var qrCodeOverlay qrCode
+
+// Show the current URL as a QR code in the middle of the TTY, so the page can be
+// opened on a phone. Any key press closes it.
+func showURLAsQRCode() {
+	code, err := encodeQRCode(CurrentTab.URI)
+	if err != nil {
+		sendMessageToWebExtension("/status,URL is too long for a QR code")
+		return
+	}
+	width, _ := screen.Size()
+	_, rows := focusedPaneArea()
+	size := len(code) + qrQuietZone*2
+	if size > width || (size+1)/2 > rows {
+		sendMessageToWebExtension("/status,TTY is too small to show a QR code")
+		return
+	}
+	qrCodeOverlay = code
+	renderCurrentTabWindow()
+}
+
+func hideQRCode() {
+	qrCodeOverlay = nil
+	renderCurrentTabWindow()
+}
+
+// Each TTY cell holds 2 modules, one above the other, using the same half block trick
+// as the frame's pixels.
+func overlayQRCode() {
+	if qrCodeOverlay == nil {
+		return
+	}
+	width, _ := screen.Size()
+	top, rows := focusedPaneArea()
+	size := len(qrCodeOverlay) + qrQuietZone*2
+	left := (width - size) / 2
+	top += (rows - (size+1)/2) / 2
+	isDark := func(x, y int) bool {
+		x -= qrQuietZone
+		y -= qrQuietZone
+		if x < 0 || y < 0 || x >= len(qrCodeOverlay) || y >= len(qrCodeOverlay) {
+			return false
+		}
+		return qrCodeOverlay[y][x]
+	}
+	colour := func(isDark bool) tcell.Color {
+		if isDark {
+			return tcell.ColorBlack
+		}
+		return tcell.ColorWhite
+	}
+	for y := 0; y < size; y += 2 {
+		for x := 0; x < size; x++ {
+			style := tcell.StyleDefault.
+				Background(colour(isDark(x, y))).
+				Foreground(colour(isDark(x, y+1)))
+			screen.SetContent(left+x, top+y/2, '▄', nil, style)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package browsh
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestQRCode(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "QR code tests")
+}
+
+func qrCodeToString(code qrCode) string {
+	var rows []string
+	for _, row := range code {
+		line := ""
+		for _, isDark := range row {
+			if isDark {
+				line += "#"
+			} else {
+				line += "."
+			}
+		}
+		rows = append(rows, line)
+	}
+	return strings.Join(rows, "\n") + "\n"
+}
+
+var _ = Describe("QR codes", func() {
+	// The "HELLO WORLD" example from https://www.thonky.com/qr-code-tutorial/
+	It("should calculate Reed-Solomon error correction", func() {
+		data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+		ecc := qrReedSolomonRemainder(data, qrReedSolomonDivisor(10))
+		Expect(ecc).To(Equal([]byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}))
+	})
+
+	It("should use the smallest version that fits", func() {
+		code, err := encodeQRCode("https://www.brow.sh/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(HaveLen(25))
+		code, err = encodeQRCode(strings.Repeat("a", 271))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(code).To(HaveLen(57))
+	})
+
+	It("should refuse text that's too long", func() {
+		_, err := encodeQRCode(strings.Repeat("a", 272))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should encode a URL", func() {
+		code, _ := encodeQRCode("https://www.brow.sh/")
+		expectGolden("qrcode", qrCodeToString(code))
+	})
+})
@@ -2,12 +2,15 @@ package browsh
 
 import (
 	"crypto/rand"
+	"crypto/tls"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/NYTimes/gziphandler"
@@ -17,8 +20,39 @@ import (
 )
 
 // In order to communicate between the incoming HTTP request and the websocket request to the
-// real browser to render the webpage, we keep track of requests in a map.
-var rawTextRequests = make(map[string]string)
+// real browser to render the webpage, we keep track of requests in a map. HTTP requests
+// and the websocket are served from different goroutines, so the map needs a mutex.
+var rawTextRequests = newRawTextRequestsMap()
+
+type threadSafeRawTextRequestsMap struct {
+	sync.RWMutex
+	internal map[string]string
+}
+
+func newRawTextRequestsMap() *threadSafeRawTextRequestsMap {
+	return &threadSafeRawTextRequestsMap{
+		internal: make(map[string]string),
+	}
+}
+
+func (m *threadSafeRawTextRequestsMap) load(key string) (value string, ok bool) {
+	m.RLock()
+	result, ok := m.internal[key]
+	m.RUnlock()
+	return result, ok
+}
+
+func (m *threadSafeRawTextRequestsMap) store(key string, value string) {
+	m.Lock()
+	m.internal[key] = value
+	m.Unlock()
+}
+
+func (m *threadSafeRawTextRequestsMap) remove(key string) {
+	m.Lock()
+	delete(m.internal, key)
+	m.Unlock()
+}
 
 // HTTPServerStart starts the HTTP server is a seperate service from the usual interactive TTY
 // app. It accepts normal HTTP requests and uses the path portion of the URL as the entry to the
@@ -35,9 +69,31 @@ func HTTPServerStart() {
 	uncompressed := http.HandlerFunc(handleHTTPServerRequest)
 	limiterMiddleware := setupRateLimiter()
 	serverMux.Handle("/", limiterMiddleware.Handler(gziphandler.GzipHandler(uncompressed)))
-	if err := http.ListenAndServe(*httpServerBind+ ":"+*HTTPServerPort, &slashFix{serverMux}); err != nil {
+	server := &http.Server{Handler: requireHTTPServerToken(&slashFix{serverMux})}
+	listener, isSocketActivated, err := systemdListener()
+	if err != nil {
+		Shutdown(err)
+	}
+	if isSocketActivated {
+		Log("Using the socket passed by systemd")
+	} else {
+		listener, err = net.Listen("tcp", *httpServerBind+":"+*HTTPServerPort)
+		if err != nil {
+			Shutdown(err)
+		}
+	}
+	if *isHTTPServerTLS {
+		config, err := httpServerTLSConfig()
+		if err != nil {
+			Shutdown(err)
+		}
+		listener = tls.NewListener(listener, config)
+	}
+	go shutdownGracefullyOnSignals(server)
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
 		Shutdown(err)
 	}
+	select {}
 }
 
 func setupRateLimiter() *stdlib.Middleware {
@@ -164,9 +220,9 @@ func waitForResponse(rawTextRequestID string, w http.ResponseWriter) {
 	var rawTextRequestResponse string
 	var ok bool
 	for {
-		if rawTextRequestResponse, ok = rawTextRequests[rawTextRequestID]; ok {
+		if rawTextRequestResponse, ok = rawTextRequests.load(rawTextRequestID); ok {
 			io.WriteString(w, rawTextRequestResponse)
-			delete(rawTextRequests, rawTextRequestID)
+			rawTextRequests.remove(rawTextRequestID)
 			break
 		}
 		time.Sleep(10 * time.Millisecond)
@@ -1,7 +1,12 @@
 package browsh
 
 import (
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -36,4 +41,45 @@ var _ = Describe("Raw text server", func() {
 			Expect(url).To(Equal(google))
 		})
 	})
+
+	Describe("Waiting for raw text", func() {
+		It("should respond with text stored from another goroutine", func() {
+			recorder := httptest.NewRecorder()
+			go rawTextRequests.store("test-request", "Some text")
+			waitForResponse("test-request", recorder)
+			Expect(recorder.Body.String()).To(Equal("Some text"))
+			_, ok := rawTextRequests.load("test-request")
+			Expect(ok).To(BeFalse())
+		})
+	})
+	Describe("Security", func() {
+		AfterEach(func() {
+			*httpServerToken = ""
+		})
+
+		It("should only answer requests with the token", func() {
+			*httpServerToken = "secret"
+			handler := requireHTTPServerToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "Some text")
+			}))
+			request := httptest.NewRequest("GET", "/https://brow.sh", nil)
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			Expect(recorder.Code).To(Equal(http.StatusUnauthorized))
+			request.Header.Set("Authorization", "Bearer secret")
+			recorder = httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			Expect(recorder.Body.String()).To(Equal("Some text"))
+		})
+
+		It("should make a self-signed certificate for localhost", func() {
+			now := time.Now()
+			certificate, err := selfSignedCertificate(now)
+			Expect(err).NotTo(HaveOccurred())
+			parsed, _ := x509.ParseCertificate(certificate.Certificate[0])
+			Expect(parsed.VerifyHostname("localhost")).To(Succeed())
+			Expect(parsed.VerifyHostname("127.0.0.1")).To(Succeed())
+			Expect(parsed.NotAfter.After(now)).To(BeTrue())
+		})
+	})
 })
@@ -0,0 +1,212 @@
+package browsh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell"
+)
+
+// Sessions can be recorded with --record and played back with --replay, which is
+// handy for reproducing bugs in how input reaches pages. Events are timed from when
+// the browser first connects, as nothing can be done with them before then, and
+// Firefox takes a varying amount of time to start. See recording_crypto.go for how
+// they're encrypted.
+type recordedEvent struct {
+	// Milliseconds since the browser connected
+	Time    int64  `json:"time"`
+	Type    string `json:"type"`
+	Key     int    `json:"key,omitempty"`
+	Char    string `json:"char,omitempty"`
+	Mod     int    `json:"mod,omitempty"`
+	X       int    `json:"x,omitempty"`
+	Y       int    `json:"y,omitempty"`
+	Buttons int    `json:"buttons,omitempty"`
+}
+
+type eventRecorder struct {
+	sync.Mutex
+	file   *os.File
+	writer io.Writer
+	start  time.Time
+}
+
+var (
+	sessionRecorder             = &eventRecorder{}
+	startRecordingAndReplayOnce sync.Once
+)
+
+func startRecordingAndReplay() {
+	startRecordingAndReplayOnce.Do(func() {
+		if *recordFile != "" {
+			passphrase := os.Getenv(recordingPassphraseVariable)
+			if err := sessionRecorder.begin(*recordFile, passphrase); err != nil {
+				logError("Couldn't start recording: " + err.Error())
+			}
+		}
+		if *replayFile != "" {
+			go replayFromFile(*replayFile)
+		}
+	})
+}
+
+// Without a passphrase, events are saved as plain JSON lines
+func (r *eventRecorder) begin(path, passphrase string) error {
+	r.Lock()
+	defer r.Unlock()
+	// Recordings can hold anything typed into a page, so only the user can read them
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	var writer io.Writer = file
+	if passphrase != "" {
+		if writer, err = newSealedWriter(file, passphrase); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	r.file = file
+	r.writer = writer
+	r.start = time.Now()
+	return nil
+}
+
+func (r *eventRecorder) record(ev tcell.Event) {
+	r.Lock()
+	defer r.Unlock()
+	if r.writer == nil {
+		return
+	}
+	if _, isKey := ev.(*tcell.EventKey); isKey && isTypingPassword() {
+		return
+	}
+	recorded, ok := newRecordedEvent(ev, r.start)
+	if !ok {
+		return
+	}
+	marshalled, _ := json.Marshal(recorded)
+	if _, err := r.writer.Write(append(marshalled, '\n')); err != nil {
+		logError("Recording stopped: " + err.Error())
+		r.file.Close()
+		r.writer = nil
+	}
+}
+
+// Encrypted recordings have their end sealed, so that replay can tell they weren't
+// cut short
+func (r *eventRecorder) end() {
+	r.Lock()
+	defer r.Unlock()
+	if r.writer == nil {
+		return
+	}
+	if sealed, ok := r.writer.(*sealedWriter); ok {
+		if err := sealed.end(); err != nil {
+			logError("Couldn't end the recording: " + err.Error())
+		}
+	}
+	r.file.Close()
+	r.writer = nil
+}
+
+// Both Browsh's own prompts and the page's input boxes become the active input box
+// when they're focused
+func isTypingPassword() bool {
+	return activeInputBox != nil && activeInputBox.Type == "password"
+}
+
+func newRecordedEvent(ev tcell.Event, start time.Time) (recordedEvent, bool) {
+	recorded := recordedEvent{Time: int64(ev.When().Sub(start) / time.Millisecond)}
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		recorded.Type = "key"
+		recorded.Key = int(ev.Key())
+		recorded.Char = string(ev.Rune())
+		recorded.Mod = int(ev.Modifiers())
+	case *tcell.EventMouse:
+		recorded.Type = "mouse"
+		recorded.X, recorded.Y = ev.Position()
+		recorded.Buttons = int(ev.Buttons())
+		recorded.Mod = int(ev.Modifiers())
+	case *tcell.EventResize:
+		recorded.Type = "resize"
+		recorded.X, recorded.Y = ev.Size()
+	default:
+		return recorded, false
+	}
+	return recorded, true
+}
+
+// Resizes are only recorded to explain what happened to the page afterwards. The
+// real terminal can't be resized to match them.
+func (recorded recordedEvent) toTcellEvent() tcell.Event {
+	switch recorded.Type {
+	case "key":
+		var char rune
+		for _, c := range recorded.Char {
+			char = c
+			break
+		}
+		return tcell.NewEventKey(tcell.Key(recorded.Key), char, tcell.ModMask(recorded.Mod))
+	case "mouse":
+		return tcell.NewEventMouse(
+			recorded.X, recorded.Y, tcell.ButtonMask(recorded.Buttons), tcell.ModMask(recorded.Mod))
+	}
+	return nil
+}
+
+func readRecording(path, passphrase string) ([]recordedEvent, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if isEncryptedRecording(contents) {
+		contents, err = openSealedRecording(contents, passphrase)
+		if err == errUnfinishedRecording {
+			logWarn(err.Error())
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	var events []recordedEvent
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		var recorded recordedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &recorded); err != nil {
+			return nil, err
+		}
+		events = append(events, recorded)
+	}
+	return events, scanner.Err()
+}
+
+func replayFromFile(path string) {
+	defer recoverAndShutdown()
+	events, err := readRecording(path, os.Getenv(recordingPassphraseVariable))
+	if err != nil {
+		logError("Couldn't replay " + path + ": " + err.Error())
+		return
+	}
+	// Posted events go through the same path as the user's own key presses and clicks
+	replay(events, func(ev tcell.Event) { screen.PostEvent(ev) })
+	logInfo("Finished replaying " + path)
+}
+
+func replay(events []recordedEvent, post func(tcell.Event)) {
+	start := time.Now()
+	for _, recorded := range events {
+		ev := recorded.toTcellEvent()
+		if ev == nil {
+			continue
+		}
+		time.Sleep(time.Until(start.Add(time.Duration(recorded.Time) * time.Millisecond)))
+		post(ev)
+	}
+}
@@ -0,0 +1,175 @@
+package browsh
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Recordings hold everything typed into pages, so with a passphrase in
+// BROWSH_RECORDING_PASSPHRASE they're encrypted. The key is derived from the
+// passphrase and a random salt with PBKDF2, and each event is sealed on its own with
+// AES-GCM as it's recorded, so that a session that ends suddenly can still be
+// replayed up to that point. The events are numbered in their nonces, so they can't
+// be reordered or left out without it being noticed. Losing the last events would
+// still go unnoticed though, so when recording stops an end is sealed after them,
+// and recordings without one are replayed with a warning.
+//
+// The file is the header, the salt, then each sealed event after its length, and
+// finally the sealed end.
+const recordingPassphraseVariable = "BROWSH_RECORDING_PASSPHRASE"
+
+const (
+	encryptedRecordingHeader = "BROWSH ENCRYPTED RECORDING 1\n"
+	recordingSaltSize        = 16
+	recordingKeyIterations   = 100000
+)
+
+var errWrongRecordingPassphrase = errors.New(
+	"The recording couldn't be decrypted, is " + recordingPassphraseVariable + " right?")
+
+var errUnfinishedRecording = errors.New(
+	"The recording has no end, so it may have been cut short and be missing its last events")
+
+// Sealed along with each record, so that an event can't be passed off as the end
+var (
+	recordedEventData = []byte("event")
+	recordingEndData  = []byte("end")
+)
+
+type sealedWriter struct {
+	writer   io.Writer
+	aead     cipher.AEAD
+	sequence uint64
+}
+
+func newSealedWriter(writer io.Writer, passphrase string) (*sealedWriter, error) {
+	salt := make([]byte, recordingSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := recordingCipher(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(append([]byte(encryptedRecordingHeader), salt...)); err != nil {
+		return nil, err
+	}
+	return &sealedWriter{writer: writer, aead: aead}, nil
+}
+
+// Each write is sealed as a single event
+func (s *sealedWriter) Write(event []byte) (int, error) {
+	if err := s.seal(event, recordedEventData); err != nil {
+		return 0, err
+	}
+	return len(event), nil
+}
+
+func (s *sealedWriter) end() error {
+	return s.seal(nil, recordingEndData)
+}
+
+func (s *sealedWriter) seal(record, data []byte) error {
+	sealed := s.aead.Seal(nil, recordingNonce(s.sequence), record, data)
+	s.sequence++
+	length := make([]byte, 4)
+	binary.BigEndian.PutUint32(length, uint32(len(sealed)))
+	_, err := s.writer.Write(append(length, sealed...))
+	return err
+}
+
+func isEncryptedRecording(contents []byte) bool {
+	return bytes.HasPrefix(contents, []byte(encryptedRecordingHeader))
+}
+
+// Returns the events as they were before they were sealed. A recording without an
+// end still returns its events, along with errUnfinishedRecording.
+func openSealedRecording(contents []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("The recording is encrypted, set " + recordingPassphraseVariable + " to replay it")
+	}
+	contents = contents[len(encryptedRecordingHeader):]
+	if len(contents) < recordingSaltSize {
+		return nil, errors.New("The recording is cut short")
+	}
+	aead, err := recordingCipher(passphrase, contents[:recordingSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	contents = contents[recordingSaltSize:]
+	var opened []byte
+	for sequence := uint64(0); len(contents) > 0; sequence++ {
+		if len(contents) < 4 {
+			return nil, errors.New("The recording is cut short")
+		}
+		length := int(binary.BigEndian.Uint32(contents))
+		contents = contents[4:]
+		if len(contents) < length {
+			return nil, errors.New("The recording is cut short")
+		}
+		sealed := contents[:length]
+		contents = contents[length:]
+		event, err := aead.Open(nil, recordingNonce(sequence), sealed, recordedEventData)
+		if err == nil {
+			opened = append(opened, event...)
+			continue
+		}
+		if _, err := aead.Open(nil, recordingNonce(sequence), sealed, recordingEndData); err != nil {
+			return nil, errWrongRecordingPassphrase
+		}
+		if len(contents) > 0 {
+			return nil, errors.New("The recording carries on after its end")
+		}
+		return opened, nil
+	}
+	return opened, errUnfinishedRecording
+}
+
+func recordingCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := pbkdf2SHA256([]byte(passphrase), salt, recordingKeyIterations, 32)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Every recording has its own salt, and so its own key, so counting from 0 in each
+// never reuses a nonce
+func recordingNonce(sequence uint64) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[4:], sequence)
+	return nonce
+}
+
+// PBKDF2 from RFC 8018, with HMAC-SHA256
+func pbkdf2SHA256(password, salt []byte, iterations, keyLength int) []byte {
+	mac := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLength; block++ {
+		mac.Reset()
+		mac.Write(salt)
+		counter := make([]byte, 4)
+		binary.BigEndian.PutUint32(counter, block)
+		mac.Write(counter)
+		u := mac.Sum(nil)
+		t := append([]byte{}, u...)
+		for i := 1; i < iterations; i++ {
+			mac.Reset()
+			mac.Write(u)
+			u = mac.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLength]
+}
@@ -0,0 +1,146 @@
+package browsh
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRecording(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Recording tests")
+}
+
+var _ = Describe("Session recording", func() {
+	var dir string
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "browsh-recording")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should replay the key presses and clicks that were recorded", func() {
+		path := filepath.Join(dir, "session.jsonl")
+		recorder := &eventRecorder{}
+		Expect(recorder.begin(path, "")).To(Succeed())
+		recorder.record(tcell.NewEventKey(tcell.KeyRune, 'é', tcell.ModAlt))
+		recorder.record(tcell.NewEventResize(80, 24))
+		recorder.record(tcell.NewEventMouse(3, 4, tcell.Button1, tcell.ModNone))
+		recorder.file.Close()
+
+		events, err := readRecording(path, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(3))
+		Expect(events[1].Type).To(Equal("resize"))
+
+		var replayed []tcell.Event
+		replay(events, func(ev tcell.Event) { replayed = append(replayed, ev) })
+		Expect(replayed).To(HaveLen(2))
+		key := replayed[0].(*tcell.EventKey)
+		Expect(key.Rune()).To(Equal('é'))
+		Expect(key.Modifiers()).To(Equal(tcell.ModAlt))
+		mouse := replayed[1].(*tcell.EventMouse)
+		x, y := mouse.Position()
+		Expect([]int{x, y}).To(Equal([]int{3, 4}))
+		Expect(mouse.Buttons()).To(Equal(tcell.Button1))
+	})
+
+	It("should keep the time between events", func() {
+		events := []recordedEvent{
+			{Time: 0, Type: "key", Key: int(tcell.KeyEnter)},
+			{Time: 50, Type: "key", Key: int(tcell.KeyEnter)},
+		}
+		var times []time.Time
+		replay(events, func(ev tcell.Event) { times = append(times, time.Now()) })
+		Expect(times[1].Sub(times[0])).To(BeNumerically(">=", 45*time.Millisecond))
+	})
+
+	It("should leave out what's typed into password boxes", func() {
+		path := filepath.Join(dir, "session.jsonl")
+		recorder := &eventRecorder{}
+		Expect(recorder.begin(path, "")).To(Succeed())
+		password := newInputBox("password")
+		password.Type = "password"
+		activeInputBox = password
+		defer func() { activeInputBox = nil }()
+		recorder.record(tcell.NewEventKey(tcell.KeyRune, 's', tcell.ModNone))
+		recorder.record(tcell.NewEventMouse(3, 4, tcell.Button1, tcell.ModNone))
+		activeInputBox = nil
+		recorder.record(tcell.NewEventKey(tcell.KeyRune, 'q', tcell.ModNone))
+		recorder.file.Close()
+
+		events, err := readRecording(path, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Type).To(Equal("mouse"))
+		Expect(events[1].Char).To(Equal("q"))
+		info, _ := os.Stat(path)
+		if runtime.GOOS != "windows" {
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		}
+	})
+
+	It("should encrypt recordings when there's a passphrase", func() {
+		path := filepath.Join(dir, "session.jsonl")
+		recorder := &eventRecorder{}
+		Expect(recorder.begin(path, "correct horse")).To(Succeed())
+		recorder.record(tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone))
+		recorder.record(tcell.NewEventMouse(3, 4, tcell.Button1, tcell.ModNone))
+		recorder.end()
+
+		contents, _ := ioutil.ReadFile(path)
+		Expect(string(contents)).NotTo(ContainSubstring(`"char":"x"`))
+		events, err := readRecording(path, "correct horse")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(2))
+		Expect(events[0].Char).To(Equal("x"))
+		Expect(events[1].Type).To(Equal("mouse"))
+
+		_, err = readRecording(path, "battery staple")
+		Expect(err).To(MatchError(errWrongRecordingPassphrase))
+		_, err = readRecording(path, "")
+		Expect(err).To(MatchError(ContainSubstring(recordingPassphraseVariable)))
+	})
+
+	It("should notice an encrypted recording that's cut short between events", func() {
+		path := filepath.Join(dir, "session.jsonl")
+		recorder := &eventRecorder{}
+		Expect(recorder.begin(path, "correct horse")).To(Succeed())
+		recorder.record(tcell.NewEventKey(tcell.KeyRune, 'x', tcell.ModNone))
+		recorder.record(tcell.NewEventKey(tcell.KeyRune, 'y', tcell.ModNone))
+		recorder.end()
+		contents, _ := ioutil.ReadFile(path)
+		_, err := openSealedRecording(contents, "correct horse")
+		Expect(err).NotTo(HaveOccurred())
+
+		firstEvent := len(encryptedRecordingHeader) + recordingSaltSize
+		cut := firstEvent + 4 + int(binary.BigEndian.Uint32(contents[firstEvent:]))
+		opened, err := openSealedRecording(contents[:cut], "correct horse")
+		Expect(err).To(MatchError(errUnfinishedRecording))
+		Expect(string(opened)).To(ContainSubstring(`"char":"x"`))
+		Expect(string(opened)).NotTo(ContainSubstring(`"char":"y"`))
+
+		Expect(ioutil.WriteFile(path, contents[:cut], 0600)).To(Succeed())
+		events, err := readRecording(path, "correct horse")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(events).To(HaveLen(1))
+	})
+
+	It("should derive keys with PBKDF2", func() {
+		key := pbkdf2SHA256([]byte("password"), []byte("salt"), 4096, 32)
+		Expect(hex.EncodeToString(key)).To(Equal(
+			"c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a"))
+	})
+})
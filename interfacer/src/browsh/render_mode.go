@@ -0,0 +1,79 @@
+package browsh
+
+import (
+	"github.com/gdamore/tcell"
+)
+
+// Render modes change how the page's colours are drawn, for e-ink terminals and for
+// anyone who finds the page's own colours hard to read. They're applied as the frame
+// is drawn to the TTY, so switching between them doesn't need a new frame. Monochrome
+// is white text on black without any graphics, inverted swaps every colour for its
+// opposite, and high-contrast only uses black and white, split at the brightness set
+// by --contrast-threshold.
+var renderModes = []string{"colour", "monochrome", "inverted", "high-contrast"}
+
+func isValidRenderMode(mode string) bool {
+	for _, valid := range renderModes {
+		if mode == valid {
+			return true
+		}
+	}
+	return false
+}
+
+func cycleRenderMode() {
+	for i, mode := range renderModes {
+		if mode == RenderMode {
+			RenderMode = renderModes[(i+1)%len(renderModes)]
+			break
+		}
+	}
+	sendMessageToWebExtension("/status,Render mode: " + RenderMode)
+}
+
+// Returns the colours and character to draw a cell with in the current render mode
+func renderModeCell(c cell) (tcell.Color, tcell.Color, rune) {
+	character := c.character[0]
+	switch RenderMode {
+	case "monochrome":
+		if character == '▄' {
+			character = ' '
+		}
+		return tcell.ColorWhite, tcell.ColorBlack, character
+	case "inverted":
+		return invertColour(c.fgColour), invertColour(c.bgColour), character
+	case "high-contrast":
+		bg := highContrastColour(c.bgColour)
+		// Text always goes on the opposite of its background, so it never disappears
+		// into it. Graphics cells are two pixels, so each is thresholded by itself.
+		if character == '▄' {
+			return highContrastColour(c.fgColour), bg, character
+		}
+		if bg == tcell.ColorBlack {
+			return tcell.ColorWhite, bg, character
+		}
+		return tcell.ColorBlack, bg, character
+	}
+	return c.fgColour, c.bgColour, character
+}
+
+func invertColour(colour tcell.Color) tcell.Color {
+	r, g, b := colour.RGB()
+	if r < 0 {
+		return colour
+	}
+	return tcell.NewRGBColor(255-r, 255-g, 255-b)
+}
+
+func highContrastColour(colour tcell.Color) tcell.Color {
+	r, g, b := colour.RGB()
+	if r < 0 {
+		return colour
+	}
+	// The same weights as Rec. 601 luma, as green looks far brighter than blue
+	brightness := (299*r + 587*g + 114*b) / 1000
+	if int(brightness) >= *contrastThreshold {
+		return tcell.ColorWhite
+	}
+	return tcell.ColorBlack
+}
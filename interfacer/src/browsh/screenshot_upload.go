@@ -0,0 +1,66 @@
+package browsh
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/gdamore/tcell"
+)
+
+// Share a screenshot by running the user's upload command on it. Anything could be
+// at the other end, scp, S3, a pastebin's CLI, etc. So if the command prints
+// something that looks like a URL then that's used as the screenshot's URL, otherwise
+// the URL is built from --screenshot-url-prefix. Either way the URL is shown in the
+// status bar and copied to the clipboard.
+func uploadScreenshot(path string) {
+	defer recoverAndShutdown()
+	sendMessageToWebExtension("/status,Uploading screenshot...")
+	command := buildScreenshotUploadCommand(*screenshotUploadCommand, path)
+	out, err := exec.Command("sh", "-c", command).CombinedOutput()
+	if err != nil {
+		logError("Screenshot upload failed: " + string(out))
+		sendMessageToWebExtension("/status,Screenshot upload failed: " + err.Error())
+		return
+	}
+	url := screenshotURLFromOutput(string(out), path, *screenshotURLPrefix)
+	if url == "" {
+		sendMessageToWebExtension("/status,Screenshot uploaded")
+		return
+	}
+	sendMessageToWebExtension("/status,Screenshot uploaded to " + url)
+	// The copy is written to the terminal, so it's left to the STDIN goroutine rather
+	// than risk interleaving with tcell's own output
+	if screen != nil {
+		copied := &screenshotURLEvent{url: url}
+		copied.SetEventNow()
+		screen.PostEvent(copied)
+	}
+}
+
+type screenshotURLEvent struct {
+	tcell.EventTime
+	url string
+}
+
+func buildScreenshotUploadCommand(template, path string) string {
+	quoted := "'" + strings.Replace(path, "'", `'\''`, -1) + "'"
+	if !strings.Contains(template, "{file}") {
+		return template + " " + quoted
+	}
+	return strings.Replace(template, "{file}", quoted, -1)
+}
+
+func screenshotURLFromOutput(out, path, prefix string) string {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(line, "http://") || strings.HasPrefix(line, "https://") {
+			return line
+		}
+	}
+	if prefix == "" {
+		return ""
+	}
+	return prefix + filepath.Base(path)
+}
@@ -0,0 +1,64 @@
+package browsh
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestScreenshotUpload(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Screenshot upload tests")
+}
+
+var _ = Describe("Screenshot upload", func() {
+	Describe("Building the command", func() {
+		It("should put the quoted path where {file} is", func() {
+			command := buildScreenshotUploadCommand("scp {file} host:shots/", "/tmp/shot 1.png")
+			Expect(command).To(Equal("scp '/tmp/shot 1.png' host:shots/"))
+		})
+
+		It("should add the path to the end without {file}", func() {
+			command := buildScreenshotUploadCommand("upload", "/tmp/shot.png")
+			Expect(command).To(Equal("upload '/tmp/shot.png'"))
+		})
+
+		It("should stop a path from breaking out of its quotes", func() {
+			path := "/tmp/it's; touch pwned.png"
+			command := buildScreenshotUploadCommand("printf %s {file}", path)
+			Expect(command).To(Equal(`printf %s '/tmp/it'\''s; touch pwned.png'`))
+			if runtime.GOOS == "windows" {
+				return
+			}
+			out, err := exec.Command("sh", "-c", command).Output()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(out)).To(Equal(path))
+		})
+	})
+
+	Describe("Finding the URL", func() {
+		It("should use the last URL the command printed", func() {
+			out := strings.Join([]string{
+				"Uploading to https://example.com/upload",
+				"https://example.com/old.png",
+				"  https://example.com/shot.png  ",
+				"Done",
+			}, "\n")
+			url := screenshotURLFromOutput(out, "/tmp/shot.png", "https://prefix.com/")
+			Expect(url).To(Equal("https://example.com/shot.png"))
+		})
+
+		It("should fall back to the prefix and the file's name", func() {
+			url := screenshotURLFromOutput("Done\n", "/tmp/shot.png", "https://prefix.com/")
+			Expect(url).To(Equal("https://prefix.com/shot.png"))
+		})
+
+		It("should have no URL without one printed or a prefix", func() {
+			Expect(screenshotURLFromOutput("", "/tmp/shot.png", "")).To(Equal(""))
+		})
+	})
+})
@@ -0,0 +1,135 @@
+package browsh
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Statistics for analysing long running sessions, written to --stats-file. Bytes are
+// those sent over the websocket to and from the browser, as tcell doesn't say how much
+// it writes to the terminal.
+type sessionStats struct {
+	FramesReceived   int64
+	FramesDropped    int64
+	FramesRendered   int64
+	BytesFromBrowser int64
+	BytesToBrowser   int64
+	EventsInjected   int64
+	Errors           int64
+}
+
+type statsSnapshot struct {
+	Time             string  `json:"time"`
+	UptimeSeconds    int64   `json:"uptime_seconds"`
+	FPS              float64 `json:"fps"`
+	FramesReceived   int64   `json:"frames_received"`
+	FramesDropped    int64   `json:"frames_dropped"`
+	FramesRendered   int64   `json:"frames_rendered"`
+	BytesFromBrowser int64   `json:"bytes_from_browser"`
+	BytesToBrowser   int64   `json:"bytes_to_browser"`
+	EventsInjected   int64   `json:"events_injected"`
+	Errors           int64   `json:"errors"`
+}
+
+var (
+	stats          sessionStats
+	statsStartedAt = time.Now()
+	// For working out the FPS since the last snapshot. Snapshots are taken periodically
+	// and when Browsh shuts down, so these are guarded by a lock.
+	lastStatsAt             = time.Now()
+	lastStatsFramesRendered int64
+	lastStatsLock           sync.Mutex
+)
+
+var statsCSVHeader = []string{
+	"time", "uptime_seconds", "fps", "frames_received", "frames_dropped", "frames_rendered",
+	"bytes_from_browser", "bytes_to_browser", "events_injected", "errors",
+}
+
+// The counters are updated from the TTY, the websocket and various background
+// goroutines.
+func countStat(counter *int64, amount int) {
+	atomic.AddInt64(counter, int64(amount))
+}
+
+func takeStatsSnapshot(now time.Time) statsSnapshot {
+	rendered := atomic.LoadInt64(&stats.FramesRendered)
+	var fps float64
+	lastStatsLock.Lock()
+	if elapsed := now.Sub(lastStatsAt).Seconds(); elapsed > 0 {
+		fps = float64(rendered-lastStatsFramesRendered) / elapsed
+	}
+	lastStatsAt = now
+	lastStatsFramesRendered = rendered
+	lastStatsLock.Unlock()
+	return statsSnapshot{
+		Time:             now.UTC().Format(time.RFC3339),
+		UptimeSeconds:    int64(now.Sub(statsStartedAt).Seconds()),
+		FPS:              float64(int(fps*10)) / 10,
+		FramesReceived:   atomic.LoadInt64(&stats.FramesReceived),
+		FramesDropped:    atomic.LoadInt64(&stats.FramesDropped),
+		FramesRendered:   rendered,
+		BytesFromBrowser: atomic.LoadInt64(&stats.BytesFromBrowser),
+		BytesToBrowser:   atomic.LoadInt64(&stats.BytesToBrowser),
+		EventsInjected:   atomic.LoadInt64(&stats.EventsInjected),
+		Errors:           atomic.LoadInt64(&stats.Errors),
+	}
+}
+
+func (s statsSnapshot) csvRecord() []string {
+	return []string{
+		s.Time,
+		strconv.FormatInt(s.UptimeSeconds, 10),
+		strconv.FormatFloat(s.FPS, 'f', 1, 64),
+		strconv.FormatInt(s.FramesReceived, 10),
+		strconv.FormatInt(s.FramesDropped, 10),
+		strconv.FormatInt(s.FramesRendered, 10),
+		strconv.FormatInt(s.BytesFromBrowser, 10),
+		strconv.FormatInt(s.BytesToBrowser, 10),
+		strconv.FormatInt(s.EventsInjected, 10),
+		strconv.FormatInt(s.Errors, 10),
+	}
+}
+
+func writeStatsPeriodically() {
+	defer recoverAndShutdown()
+	for {
+		time.Sleep(time.Duration(*statsInterval) * time.Second)
+		writeStats()
+	}
+}
+
+// Snapshots are appended, so that a session's history can be analysed afterwards
+func writeStats() {
+	if err := appendStatsSnapshot(*statsFile, takeStatsSnapshot(time.Now())); err != nil {
+		logWarn("Couldn't write stats: " + err.Error())
+	}
+}
+
+func appendStatsSnapshot(path string, snapshot statsSnapshot) error {
+	_, err := os.Stat(path)
+	isNew := os.IsNotExist(err)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if !strings.HasSuffix(strings.ToLower(path), ".csv") {
+		marshalled, _ := json.Marshal(snapshot)
+		_, err = file.Write(append(marshalled, '\n'))
+		return err
+	}
+	writer := csv.NewWriter(file)
+	if isNew {
+		writer.Write(statsCSVHeader)
+	}
+	writer.Write(snapshot.csvRecord())
+	writer.Flush()
+	return writer.Error()
+}
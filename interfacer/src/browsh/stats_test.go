@@ -0,0 +1,85 @@
+package browsh
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestStats(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Stats tests")
+}
+
+var _ = Describe("Session statistics", func() {
+	var dir string
+	snapshot := statsSnapshot{
+		Time:           "2018-07-01T12:00:00Z",
+		UptimeSeconds:  60,
+		FPS:            4.5,
+		FramesReceived: 300,
+		EventsInjected: 12,
+	}
+
+	BeforeEach(func() {
+		dir, _ = ioutil.TempDir("", "browsh-stats")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should append CSV rows under a single header", func() {
+		path := filepath.Join(dir, "stats.csv")
+		Expect(appendStatsSnapshot(path, snapshot)).To(Succeed())
+		Expect(appendStatsSnapshot(path, snapshot)).To(Succeed())
+		contents, _ := ioutil.ReadFile(path)
+		row := "2018-07-01T12:00:00Z,60,4.5,300,0,0,0,0,12,0\n"
+		Expect(string(contents)).To(Equal(
+			"time,uptime_seconds,fps,frames_received,frames_dropped,frames_rendered," +
+				"bytes_from_browser,bytes_to_browser,events_injected,errors\n" + row + row))
+	})
+
+	It("should format bandwidth for the meter", func() {
+		Expect(formatBytesPerSecond(0)).To(Equal("0B/s"))
+		Expect(formatBytesPerSecond(1536)).To(Equal("1.5KB/s"))
+		Expect(formatBytesPerSecond(3 * 1024 * 1024)).To(Equal("3.0MB/s"))
+	})
+
+	It("should show the zoom and scroll position in the meter", func() {
+		t := &tab{}
+		Expect(tabPositionReading(t)).To(Equal("zoom 100%  col 0 row 0"))
+		CurrentTab = t
+		defer func() { CurrentTab = nil }()
+		handleZoomed("1", "1.2")
+		t.frame.viewport.setPosition(12, 30)
+		Expect(tabPositionReading(t)).To(Equal("zoom 120%  col 12 row 30"))
+	})
+
+	It("should append JSON lines to other files", func() {
+		path := filepath.Join(dir, "stats.log")
+		Expect(appendStatsSnapshot(path, snapshot)).To(Succeed())
+		contents, _ := ioutil.ReadFile(path)
+		Expect(string(contents)).To(HavePrefix(`{"time":"2018-07-01T12:00:00Z","uptime_seconds":60,"fps":4.5,`))
+		Expect(string(contents)).To(HaveSuffix("}\n"))
+	})
+	It("should write histograms in Prometheus' format", func() {
+		h := newHistogram("browsh_test_seconds", "A test")
+		h.observe(3 * time.Millisecond)
+		h.observe(2 * time.Second)
+		var output bytes.Buffer
+		h.write(&output)
+		Expect(output.String()).To(ContainSubstring("# TYPE browsh_test_seconds histogram\n"))
+		Expect(output.String()).To(ContainSubstring("browsh_test_seconds_bucket{le=\"0.0025\"} 0\n"))
+		Expect(output.String()).To(ContainSubstring("browsh_test_seconds_bucket{le=\"0.005\"} 1\n"))
+		Expect(output.String()).To(ContainSubstring("browsh_test_seconds_bucket{le=\"2.5\"} 2\n"))
+		Expect(output.String()).To(ContainSubstring("browsh_test_seconds_bucket{le=\"+Inf\"} 2\n"))
+		Expect(output.String()).To(HaveSuffix("browsh_test_seconds_sum 2.003\nbrowsh_test_seconds_count 2\n"))
+	})
+})
@@ -0,0 +1,81 @@
+package browsh
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-errors/errors"
+)
+
+// Support for running the HTTP server as a systemd service. See
+// contrib/systemd/ for example units.
+//
+// With socket activation systemd listens on the port itself, starting Browsh on the
+// first connection and passing the socket over as file descriptor 3. Connections
+// wait in the socket until Browsh is ready to accept them, and they carry on waiting
+// whilst it's restarted.
+const systemdFirstSocket = 3
+
+func systemdListener() (net.Listener, bool, error) {
+	pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	count, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if pid != os.Getpid() || count == 0 {
+		return nil, false, nil
+	}
+	// So that child processes, like Firefox, don't think the socket is for them
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+	if count != 1 {
+		return nil, false, errors.New("systemd passed more than one socket, Browsh only needs one")
+	}
+	listener, err := net.FileListener(os.NewFile(systemdFirstSocket, "systemd-socket"))
+	if err != nil {
+		return nil, false, err
+	}
+	return listener, true, nil
+}
+
+// Tells systemd about changes in state for services with `Type=notify`, like when
+// Browsh is ready to serve requests. It's a no-op when not run by systemd.
+func notifySystemd(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+	// Sockets in the abstract namespace start with a NUL byte
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		logWarn("Couldn't notify systemd: " + err.Error())
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte(state))
+}
+
+// Browsh's state lives in Firefox, so the simplest way to reload it, eg; after an
+// upgrade, is to restart. SIGHUP finishes the requests already being rendered, then
+// exits, for systemd to start Browsh again. With socket activation no connections
+// are refused in the meantime. SIGTERM and SIGINT stop Browsh in the same way.
+func shutdownGracefullyOnSignals(server *http.Server) {
+	defer recoverAndShutdown()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGTERM, syscall.SIGINT)
+	received := <-signals
+	logInfo(received.String() + " received, finishing requests before exiting")
+	notifySystemd("STOPPING=1")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	server.Shutdown(ctx)
+	quitFirefox()
+	Shutdown(errors.New("normal"))
+}
@@ -0,0 +1,46 @@
+package browsh
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSystemd(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Systemd tests")
+}
+
+var _ = Describe("Systemd", func() {
+	AfterEach(func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("NOTIFY_SOCKET")
+	})
+
+	It("should only use sockets meant for this process", func() {
+		os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+		os.Setenv("LISTEN_FDS", "1")
+		_, isSocketActivated, err := systemdListener()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(isSocketActivated).To(BeFalse())
+	})
+
+	It("should notify systemd of changes in state", func() {
+		path := os.TempDir() + "/browsh-notify-test.sock"
+		os.Remove(path)
+		socket, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+		Expect(err).NotTo(HaveOccurred())
+		defer socket.Close()
+		defer os.Remove(path)
+		os.Setenv("NOTIFY_SOCKET", path)
+		notifySystemd("READY=1")
+		buffer := make([]byte, 64)
+		count, _ := socket.Read(buffer)
+		Expect(string(buffer[:count])).To(Equal("READY=1"))
+	})
+})
@@ -27,11 +27,14 @@ type tab struct {
 	PageState     string `json:"page_state"`
 	StatusMessage string `json:"status_message"`
 	frame         frame
+	// The zoom factor Firefox last reported, 0 until the tab has been zoomed
+	zoom float64
 }
 
 func ensureTabExists(id int) {
 	if _, ok := Tabs[id]; !ok {
 		newTab(id)
+		fireSessionEvent("tab_opened", Tabs[id])
 		if isNewEmptyTabActive() {
 			removeTab(-1)
 		}
@@ -47,10 +50,6 @@ func newTab(id int) {
 	tabsOrder = append(tabsOrder, id)
 	Tabs[id] = &tab{
 		ID: id,
-		frame: frame{
-			xScroll: 0,
-			yScroll: 0,
-		},
 	}
 }
 
@@ -59,10 +58,14 @@ func removeTab(id int) {
 		quitBrowsh()
 	}
 	tabsDeleted = append(tabsDeleted, id)
+	if id != -1 {
+		fireSessionEvent("tab_closed", Tabs[id])
+	}
 	sendMessageToWebExtension(fmt.Sprintf("/remove_tab,%d", id))
 	nextTab()
 	removeTabIDfromTabsOrder(id)
 	delete(Tabs, id)
+	maybeUnsplitAfterTabRemoval()
 	renderUI()
 	renderCurrentTabWindow()
 }
@@ -102,22 +105,31 @@ func isNewEmptyTabActive() bool {
 }
 
 func nextTab() {
+	moveThroughTabs(1)
+}
+
+func previousTab() {
+	moveThroughTabs(-1)
+}
+
+func moveThroughTabs(step int) {
 	for i := 0; i < len(tabsOrder); i++ {
 		if tabsOrder[i] == CurrentTab.ID {
-			if i+1 == len(tabsOrder) {
-				i = 0
-			} else {
-				i++
-			}
-			sendMessageToWebExtension(fmt.Sprintf("/switch_to_tab,%d", tabsOrder[i]))
-			CurrentTab = Tabs[tabsOrder[i]]
-			renderUI()
-			renderCurrentTabWindow()
+			switchToTab(tabsOrder[(i+step+len(tabsOrder))%len(tabsOrder)])
 			break
 		}
 	}
 }
 
+func switchToTab(id int) {
+	sendMessageToWebExtension(fmt.Sprintf("/switch_to_tab,%d", id))
+	previousTab := CurrentTab
+	CurrentTab = Tabs[id]
+	keepSplitTabDistinct(previousTab)
+	renderUI()
+	renderCurrentTabWindow()
+}
+
 func isTabPreviouslyDeleted(id int) bool {
 	for i := 0; i < len(tabsDeleted); i++ {
 		if tabsDeleted[i] == id {
@@ -138,17 +150,21 @@ func parseJSONTabState(jsonString string) {
 	}
 	ensureTabExists(incoming.ID)
 	if incoming.Active && !isNewEmptyTabActive() {
+		previousTab := CurrentTab
 		CurrentTab = Tabs[incoming.ID]
+		keepSplitTabDistinct(previousTab)
 	}
 	Tabs[incoming.ID].handleStateChange(&incoming)
 }
 
 func (t *tab) handleStateChange(incoming *tab) {
+	isPageLoaded := false
 	if t.PageState != incoming.PageState {
 		// TODO: Take the browser's scroll events as lead
 		if incoming.PageState == "page_init" {
-			t.frame.yScroll = 0
+			t.frame.viewport.setPosition(0, 0)
 		}
+		isPageLoaded = incoming.PageState == "parsing_complete"
 	}
 
 	// TODO: What's the idiomatic Golang way to do this?
@@ -156,4 +172,8 @@ func (t *tab) handleStateChange(incoming *tab) {
 	t.URI = incoming.URI
 	t.PageState = incoming.PageState
 	t.StatusMessage = incoming.StatusMessage
+	if isPageLoaded {
+		noteVisit(t.Title, t.URI)
+		fireSessionEvent("page_loaded", t)
+	}
 }
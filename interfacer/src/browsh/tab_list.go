@@ -0,0 +1,75 @@
+package browsh
+
+import (
+	"github.com/gdamore/tcell"
+)
+
+// ALT+W lists every tab, as the tab bar only has room for a few of them. The arrow
+// keys choose a tab, ENTER switches to it and ESC closes the list. Lists longer than
+// the pane scroll with the selection.
+var tabList struct {
+	isVisible bool
+	selected  int
+}
+
+func openTabList() {
+	tabList.isVisible = true
+	tabList.selected = 0
+	for i, id := range tabsOrder {
+		if id == CurrentTab.ID {
+			tabList.selected = i
+		}
+	}
+	renderCurrentTabWindow()
+}
+
+func handleTabListKeyPress(ev *tcell.EventKey) {
+	count := len(tabsOrder)
+	if count == 0 {
+		tabList.isVisible = false
+		return
+	}
+	clampTabListSelection()
+	switch ev.Key() {
+	case tcell.KeyUp:
+		tabList.selected = (tabList.selected - 1 + count) % count
+	case tcell.KeyDown:
+		tabList.selected = (tabList.selected + 1) % count
+	case tcell.KeyEnter:
+		tabList.isVisible = false
+		switchToTab(tabsOrder[tabList.selected])
+	case tcell.KeyEscape:
+		tabList.isVisible = false
+	}
+	renderCurrentTabWindow()
+}
+
+func tabListLines() []string {
+	lines := make([]string, len(tabsOrder))
+	for i, id := range tabsOrder {
+		tab := Tabs[id]
+		lines[i] = tab.Title
+		if tab.URI != "" {
+			lines[i] += " - " + tab.URI
+		}
+	}
+	return lines
+}
+
+func overlayTabList() {
+	if !tabList.isVisible {
+		return
+	}
+	clampTabListSelection()
+	overlayListDialog(nil, tabListLines(), tabList.selected)
+}
+
+// Tabs can be closed by the page whilst the list is open
+func clampTabListSelection() {
+	if tabList.selected >= len(tabsOrder) {
+		tabList.selected = len(tabsOrder) - 1
+	}
+	if tabList.selected < 0 {
+		tabList.selected = 0
+	}
+}
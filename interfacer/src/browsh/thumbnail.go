@@ -0,0 +1,121 @@
+package browsh
+
+import (
+	"time"
+
+	"github.com/gdamore/tcell"
+)
+
+// The thumbnail is a small picture of the whole page in the top right corner of the
+// TTY, with the part currently on screen shown brighter than the rest. Because the
+// TTY only ever shows a window onto a page, it helps to see where that window is.
+//
+// Shrinking the page isn't free, so the thumbnail is only rebuilt every so often,
+// rather than for every frame.
+var (
+	isThumbnailVisible       = false
+	thumbnailMaxWidth        = 30
+	thumbnailRefreshInterval = time.Second
+	thumbnailCache           [][]cell
+	thumbnailBuiltAt         time.Time
+	thumbnailTabID           int
+)
+
+func toggleThumbnail() {
+	isThumbnailVisible = !isThumbnailVisible
+	thumbnailCache = nil
+	if !isThumbnailVisible {
+		screen.Clear()
+		renderUI()
+	}
+	renderCurrentTabWindow()
+}
+
+func overlayThumbnail() {
+	if !isThumbnailVisible || CurrentTab == nil || CurrentTab.frame.cells == nil {
+		return
+	}
+	if thumbnailCache == nil ||
+		thumbnailTabID != CurrentTab.ID ||
+		time.Since(thumbnailBuiltAt) > thumbnailRefreshInterval {
+		thumbnailCache = buildThumbnail(&CurrentTab.frame)
+		thumbnailBuiltAt = time.Now()
+		thumbnailTabID = CurrentTab.ID
+	}
+	width, _ := screen.Size()
+	top, _ := focusedPaneArea()
+	for y, row := range thumbnailCache {
+		left := width - len(row)
+		for x, thumbnailCell := range row {
+			style := tcell.StyleDefault.
+				Foreground(thumbnailCell.fgColour).
+				Background(thumbnailCell.bgColour)
+			screen.SetContent(left+x, top+y, thumbnailCell.character[0], nil, style)
+		}
+	}
+}
+
+// Shrink the whole frame to fit into a quarter of the TTY's width and half of the
+// focused pane's height, keeping the page's proportions. Each thumbnail cell is a
+// half block, so it holds 2 of the thumbnail's pixels.
+func buildThumbnail(f *frame) [][]cell {
+	ttyWidth, _ := screen.Size()
+	_, paneRows := focusedPaneArea()
+	if f.totalWidth <= 0 || f.totalHeight <= 0 {
+		return nil
+	}
+	width := ttyWidth / 4
+	if width > thumbnailMaxWidth {
+		width = thumbnailMaxWidth
+	}
+	// The frame's height is measured in pixels, which are half the height of a cell
+	height := (width * f.totalHeight) / f.totalWidth
+	if height > paneRows {
+		height = paneRows
+		width = (height * f.totalWidth) / f.totalHeight
+	}
+	height -= height % 2
+	if width < 1 || height < 2 {
+		return nil
+	}
+	xScroll, yScroll := f.viewport.position()
+	onScreen := func(x, row int) bool {
+		return row >= yScroll && row < yScroll+paneRows && x >= xScroll && x < xScroll+ttyWidth
+	}
+	rows := make([][]cell, height/2)
+	for y := 0; y < height; y += 2 {
+		rows[y/2] = make([]cell, width)
+		for x := 0; x < width; x++ {
+			frameX := (x * f.totalWidth) / width
+			rows[y/2][x] = cell{
+				bgColour:  f.thumbnailPixel(frameX, (y*f.totalHeight)/height, onScreen),
+				fgColour:  f.thumbnailPixel(frameX, ((y+1)*f.totalHeight)/height, onScreen),
+				character: []rune("▄"),
+			}
+		}
+	}
+	return rows
+}
+
+// Get the colour of a single pixel in the frame, dimmed if it's not currently on screen
+func (f *frame) thumbnailPixel(x, pixelY int, onScreen func(x, row int) bool) tcell.Color {
+	var colour tcell.Color
+	row := pixelY / 2
+	frameCell, ok := f.cells.load((row * f.totalWidth) + x)
+	if !ok {
+		return tcell.ColorBlack
+	}
+	// Cells are drawn with the half block trick, so the top pixel is always the
+	// background. Only half blocks have the bottom pixel as their foreground, other
+	// characters use it for the colour of their text.
+	if pixelY%2 == 1 && len(frameCell.character) > 0 && frameCell.character[0] == '▄' {
+		colour = frameCell.fgColour
+	} else {
+		colour = frameCell.bgColour
+	}
+	r, g, b := colour.RGB()
+	if onScreen(x, row) || r < 0 {
+		return colour
+	}
+	return tcell.NewRGBColor(r/3, g/3, b/3)
+}
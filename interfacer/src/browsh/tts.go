@@ -0,0 +1,139 @@
+package browsh
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Reads the current page aloud, a paragraph at a time, by sending each one to the
+// STDIN of --tts-command. The command is run directly rather than through a shell, so
+// that pausing can stop it mid-sentence.
+type ttsReader struct {
+	sync.Mutex
+	url        string
+	paragraphs []string
+	index      int
+	isPlaying  bool
+	cmd        *exec.Cmd
+	// Incremented every time playback is started or stopped, so that an old playback
+	// knows to finish once its current paragraph has been interrupted.
+	generation int
+}
+
+var pageReader = &ttsReader{}
+
+// ALT+A starts reading the current page, or pauses and resumes if it's already being
+// read.
+func toggleReadAloud() {
+	if strings.TrimSpace(*ttsCommand) == "" {
+		sendMessageToWebExtension("/status,Set --tts-command to read pages aloud, eg; --tts-command espeak-ng")
+		return
+	}
+	pageReader.Lock()
+	isSamePage := pageReader.url == CurrentTab.URI && len(pageReader.paragraphs) > 0
+	isPlaying := pageReader.isPlaying
+	pageReader.Unlock()
+	switch {
+	case isPlaying:
+		pageReader.stop()
+		sendMessageToWebExtension("/status,Paused reading aloud")
+	case isSamePage:
+		pageReader.play()
+	default:
+		if requireWebextFeature("read_aloud") {
+			sendMessageToWebExtension("/tab_command,/read_aloud")
+		}
+	}
+}
+
+func handleParagraphs(jsonString string) {
+	var paragraphs []string
+	if err := json.Unmarshal([]byte(jsonString), &paragraphs); err != nil {
+		Shutdown(err)
+	}
+	if len(paragraphs) == 0 {
+		sendMessageToWebExtension("/status,There's no text on this page to read aloud")
+		return
+	}
+	pageReader.stop()
+	pageReader.Lock()
+	pageReader.url = CurrentTab.URI
+	pageReader.paragraphs = paragraphs
+	pageReader.index = 0
+	pageReader.Unlock()
+	pageReader.play()
+}
+
+func (r *ttsReader) play() {
+	r.Lock()
+	r.generation++
+	generation := r.generation
+	r.isPlaying = true
+	r.Unlock()
+	go r.speakFrom(generation)
+}
+
+func (r *ttsReader) stop() {
+	r.Lock()
+	defer r.Unlock()
+	r.generation++
+	r.isPlaying = false
+	if r.cmd != nil && r.cmd.Process != nil {
+		r.cmd.Process.Kill()
+	}
+}
+
+// Skip the rest of the current paragraph
+func (r *ttsReader) next() {
+	r.Lock()
+	isPlaying := r.isPlaying
+	r.Unlock()
+	if !isPlaying {
+		return
+	}
+	r.stop()
+	r.Lock()
+	r.index++
+	r.Unlock()
+	r.play()
+}
+
+func (r *ttsReader) speakFrom(generation int) {
+	defer recoverAndShutdown()
+	for {
+		r.Lock()
+		if generation != r.generation {
+			r.Unlock()
+			return
+		}
+		if r.index >= len(r.paragraphs) {
+			r.isPlaying = false
+			r.index = 0
+			r.Unlock()
+			sendMessageToWebExtension("/status,Finished reading aloud")
+			return
+		}
+		fields := strings.Fields(*ttsCommand)
+		cmd := exec.Command(fields[0], fields[1:]...)
+		cmd.Stdin = strings.NewReader(r.paragraphs[r.index])
+		if err := cmd.Start(); err != nil {
+			r.isPlaying = false
+			r.Unlock()
+			sendMessageToWebExtension("/status,Couldn't run --tts-command: " + err.Error())
+			return
+		}
+		r.cmd = cmd
+		sendMessageToWebExtension(fmt.Sprintf(
+			"/status,Reading paragraph %d of %d aloud", r.index+1, len(r.paragraphs)))
+		r.Unlock()
+		cmd.Wait()
+		r.Lock()
+		if generation == r.generation {
+			r.index++
+		}
+		r.Unlock()
+	}
+}
@@ -0,0 +1,62 @@
+package browsh
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestTTS(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Text to speech tests")
+}
+
+var _ = Describe("Reading pages aloud", func() {
+	var reader *ttsReader
+
+	BeforeEach(func() {
+		*ttsCommand = "sleep 0.2"
+		reader = &ttsReader{paragraphs: []string{"One", "Two", "Three"}}
+	})
+
+	AfterEach(func() {
+		reader.stop()
+		*ttsCommand = ""
+	})
+
+	isPlaying := func() bool {
+		reader.Lock()
+		defer reader.Unlock()
+		return reader.isPlaying
+	}
+	index := func() int {
+		reader.Lock()
+		defer reader.Unlock()
+		return reader.index
+	}
+
+	It("should resume from the paragraph it was paused on", func() {
+		reader.play()
+		Eventually(index, time.Second).Should(Equal(1))
+		reader.stop()
+		Consistently(index, 300*time.Millisecond).Should(Equal(1))
+		reader.play()
+		Eventually(index, time.Second).Should(Equal(2))
+	})
+
+	It("should skip to the next paragraph", func() {
+		reader.play()
+		reader.next()
+		Expect(index()).To(Equal(1))
+		Expect(isPlaying()).To(BeTrue())
+	})
+
+	It("should stop after the last paragraph", func() {
+		reader.index = 2
+		reader.play()
+		Eventually(isPlaying, time.Second).Should(BeFalse())
+		Expect(index()).To(Equal(0))
+	})
+})
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gdamore/tcell"
 	"github.com/go-errors/errors"
@@ -12,8 +15,8 @@ import (
 var (
 	screen   tcell.Screen
 	uiHeight = 2
-	// IsMonochromeMode decides whether to render the TTY in full colour or monochrome
-	IsMonochromeMode = false
+	// RenderMode is one of renderModes, it's exported so tests can reset it
+	RenderMode = "colour"
 )
 
 func setupTcell() {
@@ -22,6 +25,11 @@ func setupTcell() {
 		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
+	colours := fmt.Sprintf("Rendering with %d colours, TERM=%s", screen.Colors(), os.Getenv("TERM"))
+	if unknownTerm != "" {
+		colours += ", as TERM=" + unknownTerm + " isn't known"
+	}
+	logInfo(colours)
 	screen.EnableMouse()
 	screen.Clear()
 }
@@ -35,50 +43,89 @@ func sendTtySize() {
 // This is basically a proxy that listens to STDIN and forwards all relevant input
 // from the user to the webextension. So keyboard, mouse, terminal resizes, etc.
 func readStdin() {
+	defer recoverAndShutdown()
 	for {
 		ev := screen.PollEvent()
+		start := time.Now()
+		if *recordFile != "" {
+			sessionRecorder.record(ev)
+		}
 		switch ev := ev.(type) {
 		case *tcell.EventKey:
+			noteKeyPressForBandwidthMeter(ev)
 			handleUserKeyPress(ev)
 		case *tcell.EventResize:
 			handleTTYResize()
 		case *tcell.EventMouse:
 			handleMouseEvent(ev)
+		case *firefoxRestartEvent:
+			handleFirefoxRestart(ev)
+		case *screenshotURLEvent:
+			copyToClipboard(ev.url)
 		}
+		inputHandlingHistogram.observeSince(start)
 	}
 }
 
 func handleUserKeyPress(ev *tcell.EventKey) {
+	resumeIfPaused()
 	if CurrentTab == nil {
-		if ev.Key() == tcell.KeyCtrlQ {
+		if keyMap[keyChordFromEvent(ev)] == "quit" && !*isKioskMode {
 			quitBrowsh()
 		}
 		return
 	}
-	switch ev.Key() {
-	case tcell.KeyCtrlQ:
-		quitBrowsh()
-	case tcell.KeyCtrlL:
-		urlBarFocusToggle()
-	case tcell.KeyCtrlT:
-		createNewEmptyTab()
-	case tcell.KeyCtrlW:
-		removeTab(CurrentTab.ID)
-	case tcell.KeyBackspace, tcell.KeyBackspace2:
-		if activeInputBox == nil {
-			sendMessageToWebExtension("/tab_command,/history_back")
-		}
+	if promptInputBox.isActive {
+		handlePromptKeyPress(ev)
+		return
+	}
+	if *isKioskMode {
+		handleKioskKeyPress(ev)
+		return
 	}
-	if ev.Rune() == 'm' && ev.Modifiers() == 4 {
-		toggleMonochromeMode()
+	if isSelectingURL && handleURLSelectionKeyPress(ev) {
+		return
 	}
-	if ev.Key() == 279 && ev.Modifiers() == 0 {
-		openHelpTab()
+	if isCertErrorShown() {
+		handleCertErrorKeyPress(ev)
+		return
+	}
+	if tabList.isVisible {
+		handleTabListKeyPress(ev)
+		return
 	}
-	if ev.Key() == 9 && ev.Modifiers() == 0 {
-		nextTab()
+	if copyMode.isActive {
+		handleCopyModeKeyPress(ev)
+		return
+	}
+	if urlList.isVisible {
+		handleURLListKeyPress(ev)
+		return
+	}
+	if downloadList.isVisible {
+		handleDownloadListKeyPress(ev)
+		return
+	}
+	if clipboardHistory.isVisible {
+		handleClipboardHistoryKeyPress(ev)
+		return
+	}
+	if qrCodeOverlay != nil {
+		hideQRCode()
+		return
 	}
-	if !urlInputBox.isActive {
+	if isCommandMode {
+		handleCommandModeKeyPress(ev)
+		return
+	}
+	if handleKeyBinding(ev) {
+		return
+	}
+	isBackspace := ev.Key() == tcell.KeyBackspace || ev.Key() == tcell.KeyBackspace2
+	if isBackspace && activeInputBox == nil {
+		sendMessageToWebExtension("/tab_command,/history_back")
+	}
+	if !isUIInputBoxActive() {
 		forwardKeyPress(ev)
 	}
 	if activeInputBox != nil {
@@ -88,7 +135,21 @@ func handleUserKeyPress(ev *tcell.EventKey) {
 	}
 }
 
+// The TTY is in raw mode, so CTRL+C reaches the page rather than sending SIGINT. But
+// signals from elsewhere, like `kill` or a closing terminal, should still stop Firefox
+// and give the terminal back in a usable state.
+func quitOnSignals() {
+	defer recoverAndShutdown()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	received := <-signals
+	logInfo(received.String() + " received, quitting")
+	quitBrowsh()
+}
+
 func quitBrowsh() {
+	fireSessionEventAndWait("quit", nil)
+	pageReader.stop()
 	if !*isUseExistingFirefox {
 		quitFirefox()
 	}
@@ -96,7 +157,11 @@ func quitBrowsh() {
 }
 
 func toggleMonochromeMode() {
-	IsMonochromeMode = !IsMonochromeMode
+	if RenderMode == "monochrome" {
+		RenderMode = "colour"
+	} else {
+		RenderMode = "monochrome"
+	}
 }
 
 func openHelpTab() {
@@ -107,12 +172,19 @@ func forwardKeyPress(ev *tcell.EventKey) {
 	if isMultiLineEnter(ev) {
 		return
 	}
+	domKeyName, modifiers := domKey(ev)
 	eventMap := map[string]interface{}{
-		"key":  int(ev.Key()),
-		"char": string(ev.Rune()),
-		"mod":  int(ev.Modifiers()),
+		"key":     int(ev.Key()),
+		"char":    string(ev.Rune()),
+		"mod":     int(modifiers),
+		"dom_key": domKeyName,
+		"repeat":  keyRepeats.isRepeat(ev),
 	}
 	marshalled, _ := json.Marshal(eventMap)
+	countStat(&stats.EventsInjected, 1)
+	if *isLatencyMeasured || isMetricsEnabled() {
+		inputLatency.keyPressed(time.Now())
+	}
 	sendMessageToWebExtension("/stdin," + string(marshalled))
 }
 
@@ -126,28 +198,56 @@ func isMultiLineEnter(ev *tcell.EventKey) bool {
 }
 
 func handleScrolling(ev *tcell.EventKey) {
-	yScrollOriginal := CurrentTab.frame.yScroll
-	_, height := screen.Size()
-	height -= uiHeight
+	var yMagnitude int
+	_, height := focusedPaneArea()
 	if ev.Key() == tcell.KeyUp {
-		CurrentTab.frame.yScroll -= 2
+		yMagnitude = -2
 	}
 	if ev.Key() == tcell.KeyDown {
-		CurrentTab.frame.yScroll += 2
+		yMagnitude = 2
 	}
 	if ev.Key() == tcell.KeyPgUp {
-		CurrentTab.frame.yScroll -= height
+		yMagnitude = -height
 	}
 	if ev.Key() == tcell.KeyPgDn {
-		CurrentTab.frame.yScroll += height
+		yMagnitude = height
+	}
+	scrollFrame(yMagnitude)
+}
+
+// Terminals send an event for each turn of the wheel. Turns that scroll less than a
+// row are saved up, so that fractional --wheel-rows still scroll evenly.
+var wheelRemainder float64
+
+func handleWheel(button tcell.ButtonMask) {
+	direction := 1.0
+	if button&tcell.WheelUp != 0 {
+		direction = -1.0
 	}
-	CurrentTab.frame.limitScroll(height)
+	scrollFrame(wheelScrollRows(direction))
+}
+
+func wheelScrollRows(direction float64) int {
+	// Changing direction shouldn't first have to undo the previous direction's rows
+	if wheelRemainder*direction < 0 {
+		wheelRemainder = 0
+	}
+	wheelRemainder += direction * *wheelRows
+	rows := int(wheelRemainder)
+	wheelRemainder -= float64(rows)
+	return rows
+}
+
+func scrollFrame(yMagnitude int) {
+	_, yScrollOriginal := CurrentTab.frame.viewport.position()
+	_, height := focusedPaneArea()
+	xScroll, yScroll := CurrentTab.frame.scrollBy(yMagnitude, height)
 	sendMessageToWebExtension(
 		fmt.Sprintf(
 			"/tab_command,/scroll_status,%d,%d",
-			CurrentTab.frame.xScroll,
-			CurrentTab.frame.yScroll*2))
-	if CurrentTab.frame.yScroll != yScrollOriginal {
+			xScroll,
+			yScroll*2))
+	if yScroll != yScrollOriginal {
 		renderCurrentTabWindow()
 	}
 }
@@ -156,10 +256,36 @@ func handleMouseEvent(ev *tcell.EventMouse) {
 	if CurrentTab == nil {
 		return
 	}
+	resumeIfPaused()
 	x, y := ev.Position()
-	xInFrame := x + CurrentTab.frame.xScroll
-	yInFrame := y - uiHeight + CurrentTab.frame.yScroll
-	button := ev.Buttons()
+	button := remapMouseButtons(ev.Buttons())
+	if moveMousePointer(x, y) {
+		renderCurrentTabWindow()
+	}
+	if *isKioskMode && !isKioskMouseEventAllowed(button) {
+		return
+	}
+	if isInUnfocusedPane(y) {
+		if button == 1 {
+			switchPaneFocus()
+		}
+		return
+	}
+	if button&(tcell.WheelUp|tcell.WheelDown) != 0 {
+		if ev.Modifiers()&tcell.ModCtrl != 0 {
+			zoomAtMouse(button, y)
+		} else {
+			handleWheel(button)
+		}
+		return
+	}
+	paneTop, _ := focusedPaneArea()
+	xScroll, yScroll := CurrentTab.frame.viewport.position()
+	xInFrame := x + xScroll
+	yInFrame := y - paneTop + yScroll
+	if handleMiddleButton(button, xInFrame, yInFrame) {
+		return
+	}
 	if button == 1 {
 		CurrentTab.frame.maybeFocusInputBox(xInFrame, yInFrame)
 	}
@@ -170,12 +296,14 @@ func handleMouseEvent(ev *tcell.EventMouse) {
 		"modifiers": int(ev.Modifiers()),
 	}
 	marshalled, _ := json.Marshal(eventMap)
-	sendMessageToWebExtension("/stdin," + string(marshalled))
+	sendMouseEvent(button, "/stdin,"+string(marshalled))
 }
 
 func handleTTYResize() {
-	width, _ := screen.Size()
+	width, height := screen.Size()
 	urlInputBox.Width = width
+	promptInputBox.Y = height - 1
+	promptInputBox.Width = width - promptInputBox.X
 	screen.Sync()
 	sendTtySize()
 }
@@ -185,48 +313,74 @@ func handleTTYResize() {
 // will try to minimise rendering commands by only rendering parts of the terminal
 // that have changed.
 func renderCurrentTabWindow() {
-	var currentCell cell
-	var styling = tcell.StyleDefault
-	var runeChars []rune
-	width, height := screen.Size()
 	if CurrentTab == nil || CurrentTab.frame.cells == nil {
 		return
 	}
+	defer renderingHistogram.observeSince(time.Now())
 	CurrentTab.frame.overlayInputBoxContent()
-	for y := 0; y < height-uiHeight; y++ {
-		for x := 0; x < width; x++ {
-			currentCell = getCell(x, y)
-			runeChars = currentCell.character
-			// TODO: do this is in isCharacterTransparent()
-			if len(runeChars) == 0 {
-				continue
-			}
-			if IsMonochromeMode {
-				styling = styling.Foreground(tcell.ColorWhite)
-				styling = styling.Background(tcell.ColorBlack)
-				if runeChars[0] == '▄' {
-					runeChars[0] = ' '
-				}
-			} else {
-				styling = styling.Foreground(currentCell.fgColour)
-				styling = styling.Background(currentCell.bgColour)
-			}
-			screen.SetCell(x, y+uiHeight, styling, runeChars[0])
+	renderTabWindow(CurrentTab, focusedPaneArea)
+	if isSplit {
+		if splitTab.frame.cells != nil {
+			renderTabWindow(splitTab, unfocusedPaneArea)
 		}
+		renderSplitSeparator()
 	}
+	overlayThumbnail()
+	overlayURLSelection()
+	overlayCopyMode()
+	overlayQRCode()
+	overlayCertErrorDialog()
+	overlayTabList()
+	overlayURLList()
+	overlayDownloadList()
+	overlayClipboardHistory()
+	overlayMousePointer()
 	if activeInputBox != nil {
 		activeInputBox.renderCursor()
 	}
 	overlayPageStatusMessage()
+	overlayBandwidthMeter()
 	screen.Show()
+	countStat(&stats.FramesRendered, 1)
+}
+
+// Render the visible region of a tab's frame into a pane of the TTY
+func renderTabWindow(t *tab, area func() (int, int)) {
+	var currentCell cell
+	var styling = tcell.StyleDefault
+	var fgColour, bgColour tcell.Color
+	var character rune
+	width, _ := screen.Size()
+	top, rows := area()
+	// Take a single snapshot of the scroll so that the whole window is rendered from
+	// the same position, even if the browser moves it mid-render.
+	xScroll, yScroll := t.frame.viewport.position()
+	for y := 0; y < rows; y++ {
+		for x := 0; x < width; x++ {
+			currentCell = getCell(&t.frame, x, y, xScroll, yScroll)
+			// TODO: do this is in isCharacterTransparent()
+			if len(currentCell.character) == 0 {
+				continue
+			}
+			fgColour, bgColour, character = renderModeCell(currentCell)
+			styling = styling.Foreground(fgColour)
+			styling = styling.Background(bgColour)
+			screen.SetCell(x, y+top, styling, character)
+		}
+	}
 }
 
-func getCell(x, y int) cell {
+func getCell(frame *frame, x, y, xScroll, yScroll int) cell {
 	var currentCell cell
 	var ok bool
-	frame := &CurrentTab.frame
-	index := ((y + frame.yScroll) * frame.totalWidth) + (x + frame.xScroll)
-	if currentCell, ok = frame.cells.load(index); !ok {
+	index := ((y + yScroll) * frame.totalWidth) + (x + xScroll)
+	// Without this check, a TTY wider than the frame would wrap onto the next row
+	if x+xScroll >= frame.totalWidth {
+		ok = false
+	} else {
+		currentCell, ok = frame.cells.load(index)
+	}
+	if !ok {
 		fgColour, bgColour := getHatchedCellColours(x)
 		currentCell = cell{
 			fgColour:  fgColour,
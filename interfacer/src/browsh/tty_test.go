@@ -0,0 +1,269 @@
+package browsh
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "Rewrite the renderer's golden files")
+
+func TestRenderer(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Renderer tests")
+}
+
+// A 4x4 cell frame with a little bit of text in it. Pixels are a simple gradient so
+// that any misplaced cell shows up as a colour change.
+func goldenFrameFixture() (incomingFrameText, incomingFramePixels) {
+	meta := jsonFrameBase{
+		TabID:       1,
+		SubWidth:    4,
+		SubHeight:   8,
+		TotalWidth:  4,
+		TotalHeight: 8,
+	}
+	text := incomingFrameText{
+		Meta: meta,
+		Text: []string{
+			"H", "i", "", "",
+			"", "", "o", "k",
+			"", "", "", "",
+			"", "é", "", "",
+		},
+	}
+	for i := range text.Text {
+		text.Colours = append(text.Colours, int32(i*10), 0, 255)
+	}
+	pixels := incomingFramePixels{Meta: meta}
+	for y := 0; y < meta.SubHeight; y++ {
+		for x := 0; x < meta.SubWidth; x++ {
+			pixels.Colours = append(pixels.Colours, int32(x*60), int32(y*30), 100)
+		}
+	}
+	return text, pixels
+}
+
+func colourForGolden(colour tcell.Color) string {
+	if colour == tcell.ColorDefault {
+		return "default"
+	}
+	return fmt.Sprintf("%06x", colour.Hex())
+}
+
+// Serialise the simulated TTY into something that's readable in a diff. First all the
+// characters, then the foreground/background colours of every cell.
+func serialiseScreen(simScreen tcell.SimulationScreen) string {
+	var characters, colours string
+	cells, width, _ := simScreen.GetContents()
+	for i, simCell := range cells {
+		fg, bg, _ := simCell.Style.Decompose()
+		characters += string(simCell.Runes)
+		colours += colourForGolden(fg) + "/" + colourForGolden(bg)
+		if (i+1)%width == 0 {
+			characters += "\n"
+			colours += "\n"
+		} else {
+			colours += " "
+		}
+	}
+	return characters + "\n" + colours
+}
+
+func renderForGolden(width, height int) string {
+	simScreen := tcell.NewSimulationScreen("UTF-8")
+	simScreen.Init()
+	simScreen.SetSize(width, height)
+	screen = simScreen
+	CurrentTab = Tabs[1]
+	renderCurrentTabWindow()
+	return serialiseScreen(simScreen)
+}
+
+func expectGolden(name, actual string) {
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *updateGolden {
+		Expect(ioutil.WriteFile(path, []byte(actual), 0644)).To(Succeed())
+	}
+	expected, err := ioutil.ReadFile(path)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(strings.Split(actual, "\n")).To(Equal(strings.Split(string(expected), "\n")))
+}
+
+var _ = Describe("Rendering a frame to the TTY", func() {
+	BeforeEach(func() {
+		newTab(1)
+		text, pixels := goldenFrameFixture()
+		Tabs[1].frame.buildFrameText(text)
+		Tabs[1].frame.buildFramePixels(pixels)
+	})
+
+	AfterEach(func() {
+		RenderMode = "colour"
+		screen = nil
+		CurrentTab = nil
+		Tabs = make(map[int]*tab)
+		tabsOrder = nil
+	})
+
+	It("should copy text in reading order, without graphics", func() {
+		Expect(frameTextBetween(&Tabs[1].frame, 1, 0, 2, 1)).To(Equal("i\n  o"))
+		Expect(frameTextBetween(&Tabs[1].frame, 0, 3, 3, 3)).To(Equal(" é"))
+	})
+
+	for _, mode := range []string{"colour", "monochrome"} {
+		mode := mode
+		Describe("In "+mode+" mode", func() {
+			BeforeEach(func() {
+				RenderMode = mode
+			})
+
+			It("should render a TTY the same size as the frame", func() {
+				expectGolden(mode+"_4x6", renderForGolden(4, 6))
+			})
+
+			It("should crop a TTY smaller than the frame", func() {
+				expectGolden(mode+"_3x4", renderForGolden(3, 4))
+			})
+
+			It("should hatch the area of a TTY larger than the frame", func() {
+				expectGolden(mode+"_6x6", renderForGolden(6, 6))
+			})
+		})
+	}
+
+	Describe("Split into two panes", func() {
+		BeforeEach(func() {
+			newTab(2)
+			Tabs[2].Title = "Other"
+			text, pixels := goldenFrameFixture()
+			text.Meta.TabID = 2
+			pixels.Meta.TabID = 2
+			Tabs[2].frame.buildFrameText(text)
+			Tabs[2].frame.buildFramePixels(pixels)
+			Tabs[2].frame.scrollBy(1, 2)
+			isSplit = true
+			splitTab = Tabs[2]
+		})
+
+		AfterEach(func() {
+			isSplit = false
+			splitTab = nil
+		})
+
+		It("should render each tab in its own pane with its own scroll", func() {
+			expectGolden("split_8x9", renderForGolden(8, 9))
+		})
+	})
+
+	Describe("Page thumbnail", func() {
+		It("should shrink the whole page and dim what's off screen", func() {
+			renderForGolden(16, 5)
+			Tabs[1].frame.scrollBy(2, 3)
+			thumbnail := buildThumbnail(&Tabs[1].frame)
+			Expect(thumbnail).To(HaveLen(1))
+			Expect(thumbnail[0]).To(HaveLen(1))
+			r, g, b := thumbnail[0][0].bgColour.RGB()
+			Expect([3]int32{r, g, b}).To(Equal([3]int32{0, 0, 33}))
+			r, g, b = thumbnail[0][0].fgColour.RGB()
+			Expect([3]int32{r, g, b}).To(Equal([3]int32{0, 120, 100}))
+		})
+	})
+
+	Describe("Detecting URLs in text", func() {
+		It("should find URLs and their positions in cells", func() {
+			found := detectURLsInRow("▄Sée https://brow.sh/docs, or www.example.com.", 3)
+			Expect(found).To(Equal([]detectedURL{
+				{url: "https://brow.sh/docs", x: 5, y: 3, length: 20},
+				{url: "www.example.com", x: 30, y: 3, length: 15},
+			}))
+		})
+
+		It("should stop URLs at transparent cells", func() {
+			found := detectURLsInRow("http://a.com▄▄next", 0)
+			Expect(found).To(HaveLen(1))
+			Expect(found[0].url).To(Equal("http://a.com"))
+		})
+	})
+
+	// These are mostly useful when run with `go test -race`
+	Describe("Scrolling whilst rendering", func() {
+		It("should keep the scroll within the frame", func() {
+			done := make(chan bool)
+			go func() {
+				for i := 0; i < 100; i++ {
+					Tabs[1].frame.scrollBy(1, 2)
+					Tabs[1].frame.viewport.setPosition(0, 0)
+				}
+				done <- true
+			}()
+			for i := 0; i < 100; i++ {
+				renderForGolden(4, 4)
+			}
+			<-done
+			_, yScroll := Tabs[1].frame.scrollBy(100, 2)
+			Expect(yScroll).To(Equal(2))
+			_, yScroll = Tabs[1].frame.scrollBy(-100, 2)
+			Expect(yScroll).To(Equal(0))
+		})
+	})
+	Describe("Scrolling with the mouse wheel", func() {
+		AfterEach(func() {
+			*wheelRows = 3
+			wheelRemainder = 0
+		})
+
+		It("should save up fractions of rows between turns", func() {
+			*wheelRows = 1.5
+			Expect(wheelScrollRows(1)).To(Equal(1))
+			Expect(wheelScrollRows(1)).To(Equal(2))
+			Expect(wheelScrollRows(-1)).To(Equal(-1))
+			Expect(wheelScrollRows(-1)).To(Equal(-2))
+		})
+	})
+
+	Describe("Following focus", func() {
+		It("should centre the focused element in the pane", func() {
+			Expect(focusScrollMagnitude(50, 52, 0, 20)).To(Equal(41))
+			Expect(focusScrollMagnitude(50, 52, 45, 20)).To(Equal(-4))
+		})
+
+		It("should keep the top of a tall element in view", func() {
+			Expect(focusScrollMagnitude(50, 100, 0, 20)).To(Equal(50))
+		})
+	})
+
+	Describe("Zooming with the mouse", func() {
+		It("should keep the row under the mouse on the same row of the pane", func() {
+			Expect(zoomAnchorScroll(30, 10, 1, 1.5)).To(Equal(35))
+			Expect(zoomAnchorScroll(30, 10, 1.5, 1)).To(Equal(10))
+		})
+	})
+
+	Describe("Panning with the keys", func() {
+		It("should keep the pane within the frame", func() {
+			xScroll, yScroll := Tabs[1].frame.panBy(100, 1, 2, 2)
+			Expect(xScroll).To(Equal(2))
+			Expect(yScroll).To(Equal(1))
+			xScroll, yScroll = Tabs[1].frame.panBy(-100, -100, 2, 2)
+			Expect(xScroll).To(Equal(0))
+			Expect(yScroll).To(Equal(0))
+		})
+	})
+
+	Describe("Zoom presets", func() {
+		It("should scale the page to fit the pane", func() {
+			f := &Tabs[1].frame
+			Expect(zoomPresetMessage("fit-width", 2, 3, f)).To(Equal("/zoom,scale,0.5"))
+			Expect(zoomPresetMessage("fit-height", 4, 8, f)).To(Equal("/zoom,scale,2"))
+			Expect(zoomPresetMessage("200%", 4, 3, f)).To(Equal("/zoom,2"))
+		})
+	})
+})
@@ -111,8 +111,29 @@ func urlBarFocus(on bool) {
 }
 
 func overlayPageStatusMessage() {
+	if promptInputBox.isActive {
+		renderPrompt()
+		return
+	}
 	_, height := screen.Size()
-	writeString(0, height-1, CurrentTab.StatusMessage, tcell.StyleDefault)
+	x := 0
+	for _, label := range statusLabels() {
+		writeString(x, height-1, label, tcell.StyleDefault.Reverse(true))
+		x += len(label) + 1
+	}
+	writeString(x, height-1, CurrentTab.StatusMessage, tcell.StyleDefault)
+}
+
+// Modes that change what Browsh does are shown before the status message
+func statusLabels() []string {
+	var labels []string
+	if isCommandMode {
+		labels = append(labels, commandModeLabel)
+	}
+	if isCapturePaused {
+		labels = append(labels, pausedLabel)
+	}
+	return labels
 }
 
 func reverseCellColour(x, y int) {
@@ -0,0 +1,126 @@
+package browsh
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell"
+)
+
+// Pages often mention URLs in plain text that aren't links, eg; in code snippets or
+// comments. Pressing ALT+H, or whatever --keys gives `select-url`, finds all the URLs
+// on screen and selects the first one, pressing it again selects the next one and
+// ENTER opens the selected URL in a new tab.
+type detectedURL struct {
+	url string
+	// TTY coordinates of the first character of the URL, relative to the focused pane
+	x int
+	y int
+	// The URL's length in TTY cells
+	length int
+}
+
+var (
+	detectedURLs     []detectedURL
+	selectedURLIndex = -1
+	urlInTextRegex   = regexp.MustCompile(`(https?://|www\.)[^\s"'<>()\[\]{}▄]+`)
+	urlTrailingPunct = ".,;:!?"
+	isSelectingURL   = false
+)
+
+// Find the URLs in a single row of text. Positions are counted in runes, as each rune
+// is a single TTY cell.
+func detectURLsInRow(row string, y int) []detectedURL {
+	var found []detectedURL
+	for _, match := range urlInTextRegex.FindAllStringIndex(row, -1) {
+		url := strings.TrimRight(row[match[0]:match[1]], urlTrailingPunct)
+		found = append(found, detectedURL{
+			url:    url,
+			x:      utf8.RuneCountInString(row[:match[0]]),
+			y:      y,
+			length: utf8.RuneCountInString(url),
+		})
+	}
+	return found
+}
+
+func detectURLsOnScreen() []detectedURL {
+	var found []detectedURL
+	width, _ := screen.Size()
+	_, rows := focusedPaneArea()
+	xScroll, yScroll := CurrentTab.frame.viewport.position()
+	for y := 0; y < rows; y++ {
+		row := make([]rune, width)
+		for x := 0; x < width; x++ {
+			character := getCell(&CurrentTab.frame, x, y, xScroll, yScroll).character
+			if len(character) == 0 {
+				row[x] = ' '
+			} else {
+				row[x] = character[0]
+			}
+		}
+		found = append(found, detectURLsInRow(string(row), y)...)
+	}
+	return found
+}
+
+func selectNextURL() {
+	if !isSelectingURL {
+		detectedURLs = detectURLsOnScreen()
+		selectedURLIndex = -1
+	}
+	if len(detectedURLs) == 0 {
+		sendMessageToWebExtension("/status,No URLs on screen")
+		return
+	}
+	isSelectingURL = true
+	selectedURLIndex = (selectedURLIndex + 1) % len(detectedURLs)
+	renderCurrentTabWindow()
+}
+
+func stopSelectingURL() {
+	isSelectingURL = false
+	detectedURLs = nil
+	selectedURLIndex = -1
+	renderCurrentTabWindow()
+}
+
+func openSelectedURL() {
+	url := detectedURLs[selectedURLIndex].url
+	if strings.HasPrefix(url, "www.") {
+		url = "http://" + url
+	}
+	stopSelectingURL()
+	sendMessageToWebExtension("/new_tab," + url)
+}
+
+// Returns true if the key press was used for selecting URLs
+func handleURLSelectionKeyPress(ev *tcell.EventKey) bool {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		openSelectedURL()
+		return true
+	case tcell.KeyEscape:
+		stopSelectingURL()
+		return true
+	}
+	if keyMap[keyChordFromEvent(ev)] == "select-url" {
+		selectNextURL()
+		return true
+	}
+	// Anything else means the user has moved on
+	stopSelectingURL()
+	return false
+}
+
+func overlayURLSelection() {
+	if !isSelectingURL || selectedURLIndex < 0 {
+		return
+	}
+	selected := detectedURLs[selectedURLIndex]
+	top, _ := focusedPaneArea()
+	for x := selected.x; x < selected.x+selected.length; x++ {
+		reverseCellColour(x, top+selected.y)
+	}
+}
@@ -0,0 +1,146 @@
+package browsh
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell"
+)
+
+// A searchable list of pages, for bookmarks and history. Typing narrows the list down,
+// matching the letters in order anywhere in the title or URL. The arrow keys choose a
+// page, ENTER opens it, CTRL+T opens it in a new tab, DELETE removes it from the list
+// and ESC closes the list.
+type pageLink struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+var urlList struct {
+	isVisible bool
+	label     string
+	query     string
+	selected  int
+	all       []pageLink
+	matches   []pageLink
+	// Removes a page from wherever the list came from, returning what's left
+	remove func(pageLink) ([]pageLink, error)
+}
+
+func openURLList(label string, pages []pageLink, remove func(pageLink) ([]pageLink, error)) {
+	urlList.isVisible = true
+	urlList.label = label
+	urlList.all = pages
+	urlList.remove = remove
+	urlList.query = ""
+	filterURLList()
+	renderCurrentTabWindow()
+}
+
+func filterURLList() {
+	urlList.matches = matchPageLinks(urlList.all, urlList.query)
+	urlList.selected = 0
+}
+
+func handleURLListKeyPress(ev *tcell.EventKey) {
+	count := len(urlList.matches)
+	switch ev.Key() {
+	case tcell.KeyUp:
+		if count > 0 {
+			urlList.selected = (urlList.selected - 1 + count) % count
+		}
+	case tcell.KeyDown:
+		if count > 0 {
+			urlList.selected = (urlList.selected + 1) % count
+		}
+	case tcell.KeyEnter:
+		if count > 0 {
+			urlList.isVisible = false
+			sendMessageToWebExtension("/url_bar," + urlList.matches[urlList.selected].URL)
+		}
+	case tcell.KeyCtrlT:
+		if count > 0 {
+			urlList.isVisible = false
+			sendMessageToWebExtension("/new_tab," + urlList.matches[urlList.selected].URL)
+		}
+	case tcell.KeyDelete:
+		if count > 0 {
+			removeFromURLList(urlList.matches[urlList.selected])
+		}
+	case tcell.KeyEscape:
+		urlList.isVisible = false
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if urlList.query != "" {
+			_, size := utf8.DecodeLastRuneInString(urlList.query)
+			urlList.query = urlList.query[:len(urlList.query)-size]
+			filterURLList()
+		}
+	case tcell.KeyRune:
+		urlList.query += string(ev.Rune())
+		filterURLList()
+	}
+	renderCurrentTabWindow()
+}
+
+func removeFromURLList(removed pageLink) {
+	remaining, err := urlList.remove(removed)
+	if err != nil {
+		sendMessageToWebExtension("/status,Couldn't remove " + removed.URL + ": " + err.Error())
+		return
+	}
+	urlList.all = remaining
+	filterURLList()
+}
+
+// Best matches first, ie; those with the fewest letters between the ones typed
+func matchPageLinks(pages []pageLink, query string) []pageLink {
+	type scored struct {
+		pageLink
+		score int
+	}
+	var matches []scored
+	for _, page := range pages {
+		if score, ok := fuzzyMatch(query, page.Title+" "+page.URL); ok {
+			matches = append(matches, scored{page, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score < matches[j].score })
+	results := make([]pageLink, len(matches))
+	for i, match := range matches {
+		results[i] = match.pageLink
+	}
+	return results
+}
+
+func fuzzyMatch(query, text string) (int, bool) {
+	text = strings.ToLower(text)
+	score, start := 0, 0
+	for i, wanted := range strings.ToLower(query) {
+		found := strings.IndexRune(text[start:], wanted)
+		if found < 0 {
+			return 0, false
+		}
+		if i > 0 {
+			score += found
+		}
+		start += found + utf8.RuneLen(wanted)
+	}
+	return score, true
+}
+
+func overlayURLList() {
+	if !urlList.isVisible {
+		return
+	}
+	query := []string{urlList.label + urlList.query}
+	if len(urlList.matches) == 0 {
+		overlayDialog(append(query, "No matches"), -1)
+		return
+	}
+	var lines []string
+	for _, page := range urlList.matches {
+		lines = append(lines, page.Title+" - "+page.URL)
+	}
+	overlayListDialog(query, lines, urlList.selected)
+}
@@ -0,0 +1,273 @@
+package browsh
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The flag package already rejects unknown flags and values of the wrong type. But it
+// happily accepts values that can only fail later, often only once Firefox has been
+// started. So check them all up front and report every problem at once, each with a
+// hint about how to fix it.
+func validateFlags() []string {
+	var problems []string
+	problems = append(problems, validatePort("websocket-port", *webSocketPort)...)
+	if *IsHTTPServer {
+		problems = append(problems, validatePort("http-server-port", *HTTPServerPort)...)
+		if *HTTPServerPort == *webSocketPort {
+			problems = append(problems, fmt.Sprintf(
+				"--http-server-port and --websocket-port are both %s. "+
+					"Choose a different port for one of them.", *webSocketPort))
+		}
+		if net.ParseIP(*httpServerBind) == nil {
+			problems = append(problems, fmt.Sprintf(
+				"--http-server-bind '%s' is not an IP address. "+
+					"Try 0.0.0.0 for all interfaces or 127.0.0.1 for just this machine.",
+				*httpServerBind))
+		}
+		if (*httpServerTLSCert == "") != (*httpServerTLSKey == "") {
+			problems = append(problems,
+				"--http-server-tls-cert and --http-server-tls-key need to be given together.")
+		} else if *httpServerTLSCert != "" && !*isHTTPServerTLS {
+			problems = append(problems,
+				"--http-server-tls-cert is only used with --http-server-tls.")
+		}
+	}
+	if *timeLimit < 0 || (*timeLimit > 0 && *timeLimit <= timeLimitWarningLength) {
+		problems = append(problems, fmt.Sprintf(
+			"--time-limit %d is not valid. Use 0 for no limit, or a number of seconds "+
+				"greater than %d, as Browsh warns %d seconds before closing.",
+			*timeLimit, timeLimitWarningLength, timeLimitWarningLength))
+	}
+	if strings.TrimSpace(*StartupURL) == "" {
+		problems = append(problems,
+			"--startup-url is empty. Use a URL like https://google.com or a search term.")
+	}
+	problems = append(problems, validateWebhookFlags()...)
+	problems = append(problems, validateXDisplay()...)
+	problems = append(problems, validateMQTTFlags()...)
+	problems = append(problems, validateKioskFlags()...)
+	problems = append(problems, validateAllowedDomains()...)
+	if *logDestination == "stderr" && !*IsHTTPServer {
+		problems = append(problems,
+			"--log-file stderr would be drawn over the page, it's only for --http-server. Use a file or 'syslog'.")
+	}
+	if _, ok := parseLogLevel(*logLevel); *logLevel != "" && !ok {
+		problems = append(problems, fmt.Sprintf(
+			"--log-level '%s' is not valid. Choose from: %s.", *logLevel, strings.Join(logLevelNames, ", ")))
+	}
+	if err := setupKeyBindings(*keyBindings); err != nil {
+		problems = append(problems, "--keys: "+err.Error()+".")
+	}
+	if *maxFPS <= 0 || *idleFPS <= 0 || *idleFPS > *maxFPS {
+		problems = append(problems, fmt.Sprintf(
+			"--max-fps %g and --idle-fps %g are not valid. Both must be above 0, and idle can't be more.",
+			*maxFPS, *idleFPS))
+	}
+	if *videoFPS <= 0 {
+		problems = append(problems, fmt.Sprintf("--video-fps %g is not valid. Use more than 0.", *videoFPS))
+	}
+	if *pauseAfter < 0 {
+		problems = append(problems, fmt.Sprintf("--pause-after %d is not valid. Use 0 or more seconds.", *pauseAfter))
+	}
+	if _, ok := colourModes[*colourMode]; !ok && *colourMode != "auto" {
+		problems = append(problems, fmt.Sprintf(
+			"--colours '%s' is not valid. Choose from: auto, truecolor, 256, 16 or 8.", *colourMode))
+	}
+	if *brightness < -100 || *brightness > 100 {
+		problems = append(problems, fmt.Sprintf("--brightness %d is not valid. Use a percent from -100 to 100.", *brightness))
+	}
+	if *contrast <= 0 || *gamma <= 0 {
+		problems = append(problems, fmt.Sprintf(
+			"--contrast %g and --gamma %g are not valid. Both must be above 0.", *contrast, *gamma))
+	}
+	if !isValidDitherMode(*ditherMode) {
+		problems = append(problems, fmt.Sprintf(
+			"--dither '%s' is not valid. Choose from: %s.", *ditherMode, strings.Join(ditherModes, ", ")))
+	}
+	if !isValidRenderMode(*initialRenderMode) {
+		problems = append(problems, fmt.Sprintf(
+			"--render-mode '%s' is not valid. Choose from: %s.", *initialRenderMode, strings.Join(renderModes, ", ")))
+	}
+	if *contrastThreshold < 0 || *contrastThreshold > 255 {
+		problems = append(problems, fmt.Sprintf(
+			"--contrast-threshold %d is not valid. Use a brightness from 0 to 255.", *contrastThreshold))
+	}
+	if err := setupMouseButtons(*mouseButtons); err != nil {
+		problems = append(problems, "--mouse-buttons: "+err.Error()+".")
+	}
+	if *wheelRows <= 0 {
+		problems = append(problems, fmt.Sprintf("--wheel-rows %g is not valid. Use a number of rows above 0.", *wheelRows))
+	}
+	if *recordFile != "" && *recordFile == *replayFile {
+		problems = append(problems, "--record and --replay can't use the same file.")
+	}
+	if *logMaxSize < 0 {
+		problems = append(problems, "--log-max-size can't be negative. Use 0 to never rotate the log.")
+	}
+	if *statsFile != "" && *statsInterval < 1 {
+		problems = append(problems, fmt.Sprintf(
+			"--stats-interval %d is not valid. Use a number of seconds, 1 or more.", *statsInterval))
+	}
+	if strings.TrimSpace(*useFFProfile) == "" {
+		problems = append(problems,
+			"--ff-profile is empty. Leave it out to use the 'default' profile.")
+	}
+	return problems
+}
+
+func validatePort(name string, port string) []string {
+	number, err := strconv.Atoi(port)
+	if err != nil || number < 1 || number > 65535 {
+		return []string{fmt.Sprintf(
+			"--%s '%s' is not a valid port. Use a number between 1 and 65535.", name, port)}
+	}
+	return nil
+}
+
+func validateWebhookFlags() []string {
+	var problems []string
+	if *webhookURL != "" {
+		parsed, err := url.Parse(*webhookURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			problems = append(problems, fmt.Sprintf(
+				"--webhook-url '%s' is not an HTTP URL. Use something like https://example.com/hook.",
+				*webhookURL))
+		}
+	}
+	if *webhookEventsFilter == "" {
+		return problems
+	}
+	for _, event := range strings.Split(*webhookEventsFilter, ",") {
+		if !isKnownWebhookEvent(strings.TrimSpace(event)) {
+			problems = append(problems, fmt.Sprintf(
+				"--webhook-events has an unknown event '%s'. Choose from: %s.",
+				strings.TrimSpace(event), strings.Join(webhookEvents, ", ")))
+		}
+	}
+	return problems
+}
+
+func isKnownWebhookEvent(event string) bool {
+	for _, known := range webhookEvents {
+		if event == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Firefox only needs an X display when it isn't headless, and then only on systems that
+// use X, Wayland's compositor is left to Firefox. If the display can't be reached then
+// Firefox would fail long after the TTY had taken over the terminal, so try connecting
+// to it first.
+func validateXDisplay() []string {
+	if *xDisplay != "" && !*isFFGui {
+		return []string{"--display is only used with --with-gui, headless Firefox doesn't need one."}
+	}
+	if !*isFFGui || *isUseExistingFirefox || runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return nil
+	}
+	display := *xDisplay
+	if display == "" {
+		display = os.Getenv("DISPLAY")
+	}
+	if display == "" && os.Getenv("WAYLAND_DISPLAY") != "" {
+		return nil
+	}
+	if display == "" {
+		return []string{"--with-gui needs an X or Wayland display. Set --display or $DISPLAY, eg; --display :0"}
+	}
+	network, address, err := xDisplayAddress(display)
+	if err != nil {
+		return []string{fmt.Sprintf(
+			"X display '%s' is not valid. Use the form [host]:display[.screen], eg; :0", display)}
+	}
+	connection, err := net.DialTimeout(network, address, 2*time.Second)
+	// Linux's X servers also listen on an abstract socket, which has no file, so the
+	// socket's file may well not exist
+	if err != nil && network == "unix" && runtime.GOOS == "linux" {
+		connection, err = net.DialTimeout(network, "@"+address, 2*time.Second)
+	}
+	if err != nil {
+		return []string{fmt.Sprintf(
+			"Can't connect to X display '%s' (%s). "+
+				"Check that its X server is running and that you're allowed to use it.", display, err)}
+	}
+	connection.Close()
+	return nil
+}
+
+// Local displays, like ":0", are Unix sockets. Remote ones, like "example.com:1.0",
+// listen on TCP port 6000 plus the display's number.
+func xDisplayAddress(display string) (string, string, error) {
+	separator := strings.LastIndex(display, ":")
+	if separator < 0 {
+		return "", "", fmt.Errorf("no display number in '%s'", display)
+	}
+	host := display[:separator]
+	number, err := strconv.Atoi(strings.SplitN(display[separator+1:], ".", 2)[0])
+	if err != nil || number < 0 {
+		return "", "", fmt.Errorf("bad display number in '%s'", display)
+	}
+	if host == "" || host == "unix" {
+		return "unix", filepath.Join("/tmp/.X11-unix", "X"+strconv.Itoa(number)), nil
+	}
+	return "tcp", net.JoinHostPort(host, strconv.Itoa(6000+number)), nil
+}
+
+func validateMQTTFlags() []string {
+	if *mqttBroker == "" {
+		return nil
+	}
+	var problems []string
+	broker, err := url.Parse(*mqttBroker)
+	if err != nil || (broker.Scheme != "tcp" && broker.Scheme != "mqtt") || broker.Port() == "" {
+		problems = append(problems, fmt.Sprintf(
+			"--mqtt-broker '%s' is not valid. Use something like tcp://example.com:1883.", *mqttBroker))
+	}
+	if strings.Trim(*mqttTopic, "/") == "" || strings.ContainsAny(*mqttTopic, "+#") {
+		problems = append(problems, fmt.Sprintf(
+			"--mqtt-topic '%s' is not valid. Use a plain topic like 'kiosks/lobby', without wildcards.",
+			*mqttTopic))
+	}
+	return problems
+}
+
+func validateKioskFlags() []string {
+	if !*isKioskMode {
+		return nil
+	}
+	var problems []string
+	if os.Getenv(kioskPassphraseVariable) == "" {
+		problems = append(problems, fmt.Sprintf(
+			"--kiosk needs a passphrase to quit with. Set it in $%s.", kioskPassphraseVariable))
+	}
+	for _, name := range strings.Split(*kioskKeys, ",") {
+		if !isKioskKeyName(strings.TrimSpace(name)) {
+			problems = append(problems, fmt.Sprintf(
+				"--kiosk-keys has an unknown key '%s'. Choose from: %s.",
+				strings.TrimSpace(name), strings.Join(kioskKeyNames(), ", ")))
+		}
+	}
+	return problems
+}
+
+func validateAllowedDomains() []string {
+	if *allowedDomains == "" {
+		return nil
+	}
+	startup, err := url.Parse(*StartupURL)
+	if err != nil || !isDomainAllowed(startup.Hostname(), *allowedDomains) {
+		return []string{fmt.Sprintf(
+			"--startup-url '%s' isn't in --allowed-domains, so it would be blocked.", *StartupURL)}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package browsh
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestValidation(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Flag validation tests")
+}
+
+var _ = Describe("Validating flags", func() {
+	var originalWebSocketPort, originalHTTPServerPort string
+	var originalTimeLimit int
+
+	BeforeEach(func() {
+		originalWebSocketPort = *webSocketPort
+		originalHTTPServerPort = *HTTPServerPort
+		originalTimeLimit = *timeLimit
+	})
+
+	AfterEach(func() {
+		*webSocketPort = originalWebSocketPort
+		*HTTPServerPort = originalHTTPServerPort
+		*timeLimit = originalTimeLimit
+		*IsHTTPServer = false
+	})
+
+	It("should accept the defaults", func() {
+		Expect(validateFlags()).To(BeEmpty())
+	})
+
+	It("should reject ports that aren't numbers", func() {
+		*webSocketPort = "http"
+		Expect(validateFlags()).To(ConsistOf(ContainSubstring("--websocket-port 'http'")))
+	})
+
+	It("should reject ports that are out of range", func() {
+		*webSocketPort = "70000"
+		Expect(validateFlags()).To(ConsistOf(ContainSubstring("between 1 and 65535")))
+	})
+
+	It("should reject the same port for the HTTP server and the websocket", func() {
+		*IsHTTPServer = true
+		*HTTPServerPort = *webSocketPort
+		Expect(validateFlags()).To(ConsistOf(ContainSubstring("are both")))
+	})
+
+	It("should reject time limits shorter than the warning", func() {
+		*timeLimit = 5
+		Expect(validateFlags()).To(ConsistOf(ContainSubstring("greater than 10")))
+	})
+
+	It("should only log to stderr from the HTTP server", func() {
+		*logDestination = "stderr"
+		defer func() { *logDestination = "" }()
+		Expect(validateFlags()).To(ConsistOf(ContainSubstring("--log-file stderr")))
+		*IsHTTPServer = true
+		Expect(validateFlags()).To(BeEmpty())
+	})
+
+	It("should reject an idle frame rate above the maximum", func() {
+		*idleFPS = 20
+		defer func() { *idleFPS = 2 }()
+		Expect(validateFlags()).To(ConsistOf(ContainSubstring("--idle-fps 20")))
+	})
+
+	It("should reject a video frame rate of 0", func() {
+		*videoFPS = 0
+		defer func() { *videoFPS = 12 }()
+		Expect(validateFlags()).To(ConsistOf(ContainSubstring("--video-fps 0")))
+	})
+
+	It("should reject a negative pause", func() {
+		*pauseAfter = -1
+		defer func() { *pauseAfter = 0 }()
+		Expect(validateFlags()).To(ConsistOf(ContainSubstring("--pause-after -1")))
+	})
+
+	It("should reject unknown render modes", func() {
+		*initialRenderMode = "sepia"
+		defer func() { *initialRenderMode = "colour" }()
+		Expect(validateFlags()).To(ConsistOf(ContainSubstring("--render-mode 'sepia'")))
+	})
+
+	It("should reject unknown webhook events", func() {
+		*webhookEventsFilter = "page_loaded, page_exploded"
+		defer func() { *webhookEventsFilter = "" }()
+		Expect(validateFlags()).To(ConsistOf(ContainSubstring("unknown event 'page_exploded'")))
+	})
+
+	It("should reject --display without --with-gui", func() {
+		*xDisplay = ":0"
+		defer func() { *xDisplay = "" }()
+		Expect(validateFlags()).To(ConsistOf(ContainSubstring("only used with --with-gui")))
+	})
+
+	It("should accept a Wayland display for --with-gui", func() {
+		*isFFGui = true
+		for _, name := range []string{"DISPLAY", "WAYLAND_DISPLAY"} {
+			original, isSet := os.LookupEnv(name)
+			defer func(name string) {
+				if isSet {
+					os.Setenv(name, original)
+				} else {
+					os.Unsetenv(name)
+				}
+			}(name)
+		}
+		defer func() { *isFFGui = false }()
+		os.Unsetenv("DISPLAY")
+		os.Setenv("WAYLAND_DISPLAY", "wayland-0")
+		Expect(validateXDisplay()).To(BeEmpty())
+	})
+
+	It("should find the address of local and remote X displays", func() {
+		network, address, _ := xDisplayAddress(":1")
+		Expect(network).To(Equal("unix"))
+		Expect(address).To(Equal("/tmp/.X11-unix/X1"))
+		network, address, _ = xDisplayAddress("example.com:2.0")
+		Expect(network).To(Equal("tcp"))
+		Expect(address).To(Equal("example.com:6002"))
+		_, _, err := xDisplayAddress("example.com")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject a startup URL that isn't allowed", func() {
+		*allowedDomains = "brow.sh"
+		defer func() { *allowedDomains = "" }()
+		Expect(validateFlags()).To(ConsistOf(ContainSubstring("isn't in --allowed-domains")))
+	})
+
+	It("should allow subdomains of allowed domains", func() {
+		Expect(isDomainAllowed("www.brow.sh", "example.com, brow.sh")).To(BeTrue())
+		Expect(isDomainAllowed("BROW.SH.", "brow.sh")).To(BeTrue())
+		Expect(isDomainAllowed("notbrow.sh", "brow.sh")).To(BeFalse())
+		Expect(isDomainAllowed("brow.sh.evil.com", "brow.sh")).To(BeFalse())
+	})
+
+	It("should report every problem at once", func() {
+		*webSocketPort = "0"
+		*timeLimit = -1
+		Expect(validateFlags()).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,6 @@
+package browsh
+
+// The same as the webextension's version in webext/manifest.json, they're released
+// together. Releases set it from the tag, which is made from the manifest's version,
+// with `-X` in .goreleaser.yml, so this is only for builds outside of a release.
+var browshVersion = "1.3.2"
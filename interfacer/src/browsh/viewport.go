@@ -0,0 +1,61 @@
+package browsh
+
+import "sync"
+
+// A viewport is the TTY's window onto a frame. Its position is changed by the user's
+// key presses on the stdin goroutine, but also by the browser over the websocket
+// goroutine (eg; a new page load resets it). So all access has to go through a lock.
+type viewport struct {
+	sync.RWMutex
+	xScroll int
+	yScroll int
+}
+
+func (v *viewport) position() (int, int) {
+	v.RLock()
+	defer v.RUnlock()
+	return v.xScroll, v.yScroll
+}
+
+func (v *viewport) setPosition(x, y int) {
+	v.Lock()
+	v.xScroll = x
+	v.yScroll = y
+	v.Unlock()
+}
+
+// Move the viewport by the given number of cells, keeping the vertical position
+// between 0 and maxYScroll. Returns the new position so that callers don't need to
+// read it again, by which time it may have changed.
+func (v *viewport) scrollBy(xMagnitude, yMagnitude, maxYScroll int) (int, int) {
+	v.Lock()
+	defer v.Unlock()
+	v.xScroll += xMagnitude
+	v.yScroll += yMagnitude
+	if v.yScroll > maxYScroll {
+		v.yScroll = maxYScroll
+	}
+	if v.yScroll < 0 {
+		v.yScroll = 0
+	}
+	return v.xScroll, v.yScroll
+}
+
+// Like scrollBy, but also keeps the horizontal position between 0 and maxXScroll
+func (v *viewport) panBy(xMagnitude, yMagnitude, maxXScroll, maxYScroll int) (int, int) {
+	v.Lock()
+	defer v.Unlock()
+	v.xScroll = clampScroll(v.xScroll+xMagnitude, maxXScroll)
+	v.yScroll = clampScroll(v.yScroll+yMagnitude, maxYScroll)
+	return v.xScroll, v.yScroll
+}
+
+func clampScroll(scroll, maxScroll int) int {
+	if scroll > maxScroll {
+		scroll = maxScroll
+	}
+	if scroll < 0 {
+		scroll = 0
+	}
+	return scroll
+}
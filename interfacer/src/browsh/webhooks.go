@@ -0,0 +1,127 @@
+package browsh
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Webhooks let unattended instances of Browsh, like kiosks, report what they're up
+// to. Each event is POSTed as JSON to --webhook-url.
+var webhookEvents = []string{
+	"browser_connected",
+	"browser_disconnected",
+	"tab_opened",
+	"tab_closed",
+	"page_loaded",
+	"download_complete",
+	"download_failed",
+	"quit",
+}
+
+var webhookClient = &http.Client{Timeout: 5 * time.Second}
+
+type webhookPayload struct {
+	Event string `json:"event"`
+	Time  string `json:"time"`
+	TabID int    `json:"tab_id,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Title string `json:"title,omitempty"`
+	// Only for download events
+	File  string `json:"file,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Session events go to every integration that's been set up
+func fireSessionEvent(event string, t *tab) {
+	fireWebhook(event, t)
+	publishMQTTEvent(event, t)
+}
+
+// Downloads aren't tied to a tab, so their events say which file it was instead
+func fireDownloadEvent(event string, d download) {
+	payload := newWebhookPayload(event, nil)
+	payload.File = d.Filename
+	payload.Error = d.Error
+	if isWebhookEventWanted(event) {
+		postWebhookInBackground(payload)
+	}
+	publishMQTTPayload(payload)
+}
+
+func fireSessionEventAndWait(event string, t *tab) {
+	fireWebhookAndWait(event, t)
+	publishMQTTEvent(event, t)
+}
+
+func isWebhookEventWanted(event string) bool {
+	if *webhookURL == "" {
+		return false
+	}
+	if *webhookEventsFilter == "" {
+		return true
+	}
+	for _, wanted := range strings.Split(*webhookEventsFilter, ",") {
+		if strings.TrimSpace(wanted) == event {
+			return true
+		}
+	}
+	return false
+}
+
+func newWebhookPayload(event string, t *tab) webhookPayload {
+	payload := webhookPayload{
+		Event: event,
+		Time:  time.Now().UTC().Format(time.RFC3339),
+	}
+	if t != nil {
+		payload.TabID = t.ID
+		payload.URL = t.URI
+		payload.Title = t.Title
+	}
+	return payload
+}
+
+// Send a webhook in the background, so that a slow endpoint can't hold up the TTY.
+// The tab can be nil for events that aren't about a tab.
+func fireWebhook(event string, t *tab) {
+	if !isWebhookEventWanted(event) {
+		return
+	}
+	postWebhookInBackground(newWebhookPayload(event, t))
+}
+
+func postWebhookInBackground(payload webhookPayload) {
+	go func() {
+		defer recoverAndShutdown()
+		if err := postWebhook(payload); err != nil {
+			logError(err.Error())
+		}
+	}()
+}
+
+// For when Browsh is about to exit and there'd be no background to send from
+func fireWebhookAndWait(event string, t *tab) {
+	if !isWebhookEventWanted(event) {
+		return
+	}
+	if err := postWebhook(newWebhookPayload(event, t)); err != nil {
+		logError(err.Error())
+	}
+}
+
+func postWebhook(payload webhookPayload) error {
+	marshalled, _ := json.Marshal(payload)
+	response, err := webhookClient.Post(*webhookURL, "application/json", bytes.NewReader(marshalled))
+	if err != nil {
+		return fmt.Errorf("Webhook for '%s' failed: %s", payload.Event, err)
+	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("Webhook for '%s' got status %d", payload.Event, response.StatusCode)
+	}
+	return nil
+}
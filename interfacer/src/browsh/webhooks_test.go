@@ -0,0 +1,73 @@
+package browsh
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestWebhooks(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Webhook tests")
+}
+
+var _ = Describe("Webhooks", func() {
+	var server *httptest.Server
+	var received chan webhookPayload
+
+	BeforeEach(func() {
+		received = make(chan webhookPayload, 10)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var payload webhookPayload
+			json.NewDecoder(r.Body).Decode(&payload)
+			received <- payload
+		}))
+		*webhookURL = server.URL
+	})
+
+	AfterEach(func() {
+		server.Close()
+		*webhookURL = ""
+		*webhookEventsFilter = ""
+	})
+
+	It("should POST the event with the tab's details", func() {
+		fireWebhook("page_loaded", &tab{ID: 3, URI: "https://www.brow.sh/", Title: "Browsh"})
+		var payload webhookPayload
+		Eventually(received).Should(Receive(&payload))
+		Expect(payload.Event).To(Equal("page_loaded"))
+		Expect(payload.TabID).To(Equal(3))
+		Expect(payload.URL).To(Equal("https://www.brow.sh/"))
+		Expect(payload.Time).NotTo(BeEmpty())
+	})
+
+	It("should only send the events that were asked for", func() {
+		*webhookEventsFilter = "quit"
+		fireWebhook("tab_opened", nil)
+		fireWebhookAndWait("quit", nil)
+		var payload webhookPayload
+		Expect(received).To(Receive(&payload))
+		Expect(payload.Event).To(Equal("quit"))
+		Consistently(received).ShouldNot(Receive())
+	})
+
+	It("should say which download finished or failed", func() {
+		defer func() { downloads.all = nil }()
+		handleDownload(`{"id": 1, "filename": "/tmp/a.zip", "state": "in_progress"}`)
+		handleDownload(`{"id": 1, "filename": "/tmp/a.zip", "state": "complete"}`)
+		var payload webhookPayload
+		Eventually(received).Should(Receive(&payload))
+		Expect(payload.Event).To(Equal("download_complete"))
+		Expect(payload.File).To(Equal("/tmp/a.zip"))
+		handleDownload(`{"id": 1, "filename": "/tmp/a.zip", "state": "complete"}`)
+		handleDownload(`{"id": 2, "filename": "/tmp/b.zip", "state": "interrupted", "error": "NETWORK_FAILED"}`)
+		Eventually(received).Should(Receive(&payload))
+		Expect(payload.Event).To(Equal("download_failed"))
+		Expect(payload.Error).To(Equal("NETWORK_FAILED"))
+		Consistently(received).ShouldNot(Receive())
+	})
+})
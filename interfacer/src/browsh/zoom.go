@@ -0,0 +1,128 @@
+package browsh
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/gdamore/tcell"
+)
+
+// Zooming makes the page taller or shorter, so without any help the part of the page
+// under the mouse drifts away as the rows above it grow or shrink. So zooming with
+// CTRL+wheel remembers which row of the page was under the mouse, and once the browser
+// says how much it zoomed by, scrolls that row back under the mouse. Text reflows as
+// it's zoomed, so it's only ever roughly the same row.
+var zoomAnchor struct {
+	isPending bool
+	// The row of the page under the mouse, and the row of the pane the mouse was on
+	row   int
+	paneY int
+}
+
+func zoomAtMouse(button tcell.ButtonMask, y int) {
+	if !requireWebextFeature("zoom") {
+		return
+	}
+	paneTop, _ := focusedPaneArea()
+	_, yScroll := CurrentTab.frame.viewport.position()
+	zoomAnchor.isPending = true
+	zoomAnchor.row = yScroll + y - paneTop
+	zoomAnchor.paneY = y - paneTop
+	if button&tcell.WheelUp != 0 {
+		sendMessageToWebExtension("/zoom,in")
+	} else {
+		sendMessageToWebExtension("/zoom,out")
+	}
+}
+
+// Every zoom is reported, whether or not it was zoomed at the mouse, so that the
+// bandwidth meter can show the zoom
+func handleZoomed(oldFactor, newFactor string) {
+	if CurrentTab == nil {
+		return
+	}
+	oldZoom, oldErr := strconv.ParseFloat(oldFactor, 64)
+	newZoom, newErr := strconv.ParseFloat(newFactor, 64)
+	if newErr == nil {
+		CurrentTab.zoom = newZoom
+	}
+	if !zoomAnchor.isPending {
+		return
+	}
+	zoomAnchor.isPending = false
+	if oldErr != nil || newErr != nil || oldZoom <= 0 {
+		return
+	}
+	_, yScroll := CurrentTab.frame.viewport.position()
+	scrollFrame(zoomAnchorScroll(zoomAnchor.row, zoomAnchor.paneY, oldZoom, newZoom) - yScroll)
+}
+
+// The scroll that puts the zoomed anchor row back on the same row of the pane
+func zoomAnchorScroll(row, paneY int, oldZoom, newZoom float64) int {
+	zoomedRow := int(math.Round(float64(row) * newZoom / oldZoom))
+	return zoomedRow - paneY
+}
+
+// Zooming in can make the page wider than the TTY, and not every terminal forwards the
+// mouse wheel, let alone sideways scrolling. So the focused pane can also be panned
+// with ALT+SHIFT and the arrow keys, or SHIFT and the arrow keys in command mode. It
+// moves by a quarter of the pane at a time, so some of what was in view stays in view.
+func panFrame(xDirection, yDirection int) {
+	if CurrentTab == nil {
+		return
+	}
+	xOriginal, yOriginal := CurrentTab.frame.viewport.position()
+	width, _ := screen.Size()
+	_, height := focusedPaneArea()
+	xScroll, yScroll := CurrentTab.frame.panBy(
+		xDirection*panStep(width), yDirection*panStep(height), width, height)
+	if xScroll == xOriginal && yScroll == yOriginal {
+		return
+	}
+	sendMessageToWebExtension(
+		fmt.Sprintf("/tab_command,/scroll_status,%d,%d", xScroll, yScroll*2))
+	renderCurrentTabWindow()
+}
+
+func panStep(size int) int {
+	if size < 4 {
+		return 1
+	}
+	return size / 4
+}
+
+// Besides stepping in and out, the zoom can jump between presets. Fitting the width
+// or height scales the page so that all of it fits across or down the focused pane,
+// or as near as it can, as text reflows at a different zoom.
+var (
+	zoomPresets     = []string{"fit-width", "fit-height", "100%", "200%"}
+	zoomPresetIndex = -1
+)
+
+func cycleZoomPreset() {
+	if CurrentTab == nil || !requireWebextFeature("zoom") {
+		return
+	}
+	zoomPresetIndex = (zoomPresetIndex + 1) % len(zoomPresets)
+	width, _ := screen.Size()
+	_, height := focusedPaneArea()
+	sendMessageToWebExtension(zoomPresetMessage(
+		zoomPresets[zoomPresetIndex], width, height, &CurrentTab.frame))
+}
+
+func zoomPresetMessage(preset string, width, height int, f *frame) string {
+	switch preset {
+	case "fit-width":
+		if f.totalWidth > 0 {
+			return fmt.Sprintf("/zoom,scale,%g", float64(width)/float64(f.totalWidth))
+		}
+	case "fit-height":
+		if f.domRowCount() > 0 {
+			return fmt.Sprintf("/zoom,scale,%g", float64(height)/float64(f.domRowCount()))
+		}
+	case "200%":
+		return "/zoom,2"
+	}
+	return "/zoom,1"
+}
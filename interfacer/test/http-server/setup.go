@@ -50,7 +50,7 @@ func getPath(path string, mode string) string {
 }
 
 var _ = ginkgo.BeforeEach(func() {
-	browsh.IsMonochromeMode = false
+	browsh.RenderMode = "colour"
 	browsh.Log("\n---------")
 	browsh.Log(ginkgo.CurrentGinkgoTestDescription().FullTestText)
 	browsh.Log("---------")
@@ -193,7 +193,7 @@ func runeCount(text string) int {
 }
 
 var _ = ginkgo.BeforeEach(func() {
-	browsh.IsMonochromeMode = false
+	browsh.RenderMode = "colour"
 	browsh.Log("\n---------")
 	browsh.Log(ginkgo.CurrentGinkgoTestDescription().FullTestText)
 	browsh.Log("---------")
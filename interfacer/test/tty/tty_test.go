@@ -39,6 +39,21 @@ var _ = Describe("Showing a basic webpage", func() {
 				Expect("Another").To(BeInFrameAt(0, 0))
 			})
 
+			It("should navigate to a new page by searching for a link's text", func() {
+				simScreen.InjectKey(tcell.KeyRune, 'l', tcell.ModAlt)
+				Keyboard("another")
+				SpecialKey(tcell.KeyEnter)
+				Expect("Another").To(BeInFrameAt(0, 0))
+			})
+
+			It("should cancel a link search with Escape", func() {
+				simScreen.InjectKey(tcell.KeyRune, 'l', tcell.ModAlt)
+				Keyboard("another")
+				SpecialKey(tcell.KeyEscape)
+				URL := testSiteURL + "/smorgasbord/"
+				Expect(URL).To(BeInFrameAt(0, 1))
+			})
+
 			It("should scroll the page by one line", func() {
 				SpecialKey(tcell.KeyDown)
 				Expect("meal,▄originating▄in▄").To(BeInFrameAt(12, 11))
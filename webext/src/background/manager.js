@@ -5,6 +5,28 @@ import TTYCommandsMixin from "background/tty_commands_mixin";
 import Tab from "background/tab";
 import Dimensions from "background/dimensions";
 
+// Bump for changes to the terminal protocol that aren't just new features
+const PROTOCOL_VERSION = 1;
+// The features that need both the webextension and the terminal
+const FEATURES = [
+  "allowed_domains",
+  "auth",
+  "cert_errors",
+  "file_input",
+  "find",
+  "follow_focus",
+  "follow_link",
+  "media",
+  "read_aloud",
+  "frame_rate",
+  "idle_pause",
+  "kiosk",
+  "reload",
+  "screenshot",
+  "user_agent",
+  "zoom"
+];
+
 // Boots the background process. Mainly involves connecting to the websocket server
 // launched by the Browsh CLI client and setting up listeners for new tabs that
 // have our webextension content script inside them.
@@ -21,19 +43,44 @@ export default class extends utils.mixins(CommonMixin, TTYCommandsMixin) {
     // Used so that reconnections to the terminal don't also attempt to reconnect to the
     // browser DOM.
     this._is_connected_to_browser_dom = false;
-    // The time in milliseconds between requesting a new TTY-size pixel frame
-    this._small_pixel_frame_rate = 250;
+    // The time in milliseconds between requesting a new TTY-size pixel frame. Once the
+    // user hasn't touched anything for a while frames are requested less often, to save
+    // bandwidth on slow connections. Set by the terminal's --max-fps and --idle-fps.
+    this._small_pixel_frame_rate = 100;
+    this._idle_small_pixel_frame_rate = 500;
+    // Just the part of the TTY that a playing video covers is requested at its own,
+    // faster, rate. Set by the terminal's --video-fps.
+    this._video_frame_rate = 1000 / 12;
+    this._idle_after = 5000;
+    this._last_user_activity = Date.now();
+    // After this long without any activity frames stop being requested at all, until
+    // there's some more. Set by the terminal's --pause-after, 0 never pauses.
+    this._pause_after = 0;
+    this._is_paused = false;
     // Raw text mode is for when Browsh is running as an HTTP server that serves single
     // pages as entire DOMs, in plain text.
     this._is_raw_text_mode = false;
+    // Kiosk mode is for public terminals, whose users mustn't be able to write files to
+    // the host, so downloads are refused. Set by the terminal's --kiosk.
+    this._is_kiosk_mode = false;
     // A mobile user agent for forcing web pages to use its mobile layout
     this._mobile_user_agent =
       "Mozilla/5.0 (Android 7.0; Mobile; rv:54.0) Gecko/58.0 Firefox/58.0";
     this._is_using_mobile_user_agent = false;
+    // Set by the terminal's --allowed-domains, pages can be loaded from anywhere if empty
+    this._allowed_domains = [];
     this._addUserAgentListener();
     // Listen to HTTP requests. This allows us to display some helpful status messages at the
     // bottom of the page, eg; "Loading https://coolwebsite.com..."
     this._addWebRequestListener();
+    // HTTP authentication requests waiting for the user to enter credentials in the TTY,
+    // keyed by the request's ID.
+    this._pending_auth_requests = {};
+    this._addAuthListener();
+    this._addCertErrorListener();
+    this._started_at = Date.now();
+    this._is_polling_downloads = false;
+    this._addDownloadListener();
     // The manager is the hub between tabs and the terminal. First we connect to the
     // terminal, as that is the process that would have initially booted the browser and
     // this very code that now runs.
@@ -42,11 +89,12 @@ export default class extends utils.mixins(CommonMixin, TTYCommandsMixin) {
 
   _connectToTerminal() {
     // This is the websocket server run by the CLI client
-    this.terminal = new WebSocket("ws://localhost:3334");
+    this.terminal = new WebSocket("ws://127.0.0.1:3334");
     this.terminal.addEventListener("open", _event => {
       this.log("Webextension connected to the terminal's websocket server");
       this.dimensions.terminal = this.terminal;
       this._listenForTerminalMessages();
+      this._sayHelloToTerminal();
       this._connectToBrowserDOM();
       this._startFrameRequestLoop();
     });
@@ -65,6 +113,17 @@ export default class extends utils.mixins(CommonMixin, TTYCommandsMixin) {
     }
   }
 
+  // Lets the terminal know what this version of the webextension can do, see the TTY's
+  // hello.go.
+  _sayHelloToTerminal() {
+    const hello = {
+      protocol: PROTOCOL_VERSION,
+      version: browser.runtime.getManifest().version,
+      features: FEATURES
+    };
+    this.sendToTerminal(`/hello,${JSON.stringify(hello)}`);
+  }
+
   // Mostly listening for forwarded STDIN from the terminal. Therefore, the user
   // pressing the arrow keys, typing, moving the mouse, etc, etc. But we also listen
   // to TTY resize events too.
@@ -241,12 +300,79 @@ export default class extends utils.mixins(CommonMixin, TTYCommandsMixin) {
   // TTY-sized text frames are sent in response to DOM mutation events.
   _startFrameRequestLoop() {
     this.log("BACKGROUND: Frame loop starting");
-    setInterval(() => {
+    this._scheduleFrameRequest();
+    this._scheduleVideoFrameRequest();
+  }
+
+  _scheduleFrameRequest() {
+    const rate = this._isUserIdle()
+      ? this._idle_small_pixel_frame_rate
+      : this._small_pixel_frame_rate;
+    this._frame_request_timer = setTimeout(() => {
       if (this._is_initial_window_size_pending) this._initialWindowResize();
-      if (this._isAbleToRequestFrame()) {
+      this._updatePaused();
+      if (!this._is_paused && this._isAbleToRequestFrame()) {
         this.sendToCurrentTab("/request_frame");
       }
-    }, this._small_pixel_frame_rate);
+      this._scheduleFrameRequest();
+    }, rate);
+  }
+
+  // Playing videos change the page without the user doing anything, so they get a
+  // heartbeat of their own, that only asks for the part of the TTY they cover.
+  _scheduleVideoFrameRequest() {
+    setTimeout(() => {
+      if (
+        !this._is_paused &&
+        this._isVideoPlaying() &&
+        this._isAbleToRequestFrame()
+      ) {
+        this.sendToCurrentTab("/request_video_frame");
+      }
+      this._scheduleVideoFrameRequest();
+    }, this._video_frame_rate);
+  }
+
+  _isVideoPlaying() {
+    const tab = this.tabs[this.active_tab_id];
+    return tab !== undefined && tab.is_video_playing;
+  }
+
+  _isUserIdle() {
+    return Date.now() - this._last_user_activity > this._idle_after;
+  }
+
+  // Nobody is watching a video, so it's safe to pause
+  _isIdle() {
+    return this._isUserIdle() && !this._isVideoPlaying();
+  }
+
+  // The terminal is told, so it can stop drawing and say that it's paused. And so are
+  // the tabs, as changes to a page's text are sent without being asked for.
+  _updatePaused() {
+    const is_paused =
+      this._pause_after > 0 &&
+      this._isIdle() &&
+      Date.now() - this._last_user_activity > this._pause_after;
+    if (is_paused !== this._is_paused) {
+      this._is_paused = is_paused;
+      this.sendToTerminal(`/paused,${is_paused}`);
+      Object.values(this.tabs).forEach(tab => {
+        if (tab.channel !== undefined) {
+          tab.channel.postMessage(`/paused,${is_paused}`);
+        }
+      });
+    }
+  }
+
+  // Don't leave the user waiting for the rest of a slow idle frame
+  noteUserActivity() {
+    const was_idle = this._isUserIdle();
+    this._last_user_activity = Date.now();
+    if (was_idle && this._frame_request_timer !== undefined) {
+      clearTimeout(this._frame_request_timer);
+      this._scheduleFrameRequest();
+    }
   }
 
   _isAbleToRequestFrame() {
@@ -272,11 +398,22 @@ export default class extends utils.mixins(CommonMixin, TTYCommandsMixin) {
     return true;
   }
 
-  // Listen for HTTP activity so we can notify the user that something is loading in the background
+  // Listen for HTTP activity so we can notify the user that something is loading in the background,
+  // and so that pages from outside --allowed-domains can be blocked.
   _addWebRequestListener() {
     browser.webRequest.onBeforeRequest.addListener(
       e => {
         let message;
+        if (!this._isURLAllowed(e.url)) {
+          if (e.type == "main_frame" && this.currentTab() !== undefined) {
+            const host = new URL(e.url).hostname;
+            this.currentTab().updateStatus(
+              "info",
+              `Blocked ${host}, it isn't in --allowed-domains`
+            );
+          }
+          return { cancel: true };
+        }
         if (e.type == "main_frame") {
           message = `Loading ${e.url}`;
           if (this.currentTab() !== undefined) {
@@ -284,8 +421,154 @@ export default class extends utils.mixins(CommonMixin, TTYCommandsMixin) {
           }
         }
       },
-      { urls: ["*://*/*"] },
+      { urls: ["*://*/*"], types: ["main_frame", "sub_frame"] },
+      ["blocking"]
+    );
+  }
+
+  // Listing a domain also allows its subdomains. Only pages and frames are checked,
+  // as pages often load their images and scripts from CDNs.
+  _isURLAllowed(url) {
+    if (this._allowed_domains.length === 0) {
+      return true;
+    }
+    const host = new URL(url).hostname.toLowerCase().replace(/\.$/, "");
+    return this._allowed_domains.some(
+      domain => host === domain || host.endsWith("." + domain)
+    );
+  }
+
+  // Firefox's own login dialog can't be used from the TTY, so hold the request whilst
+  // the credentials are asked for there instead.
+  _addAuthListener() {
+    browser.webRequest.onAuthRequired.addListener(
+      e => {
+        if (this._pending_auth_requests[e.requestId] !== undefined) {
+          return {};
+        }
+        const request = {
+          id: e.requestId,
+          host: e.challenger.host,
+          realm: e.realm || "",
+          is_proxy: e.isProxy
+        };
+        this.sendToTerminal(`/auth_required,${JSON.stringify(request)}`);
+        return new Promise(resolve => {
+          this._pending_auth_requests[e.requestId] = resolve;
+        });
+      },
+      { urls: ["<all_urls>"] },
       ["blocking"]
     );
   }
+
+  // Firefox's certificate error page isn't readable in the TTY, so the TTY shows its
+  // own dialog instead.
+  _addCertErrorListener() {
+    browser.webRequest.onErrorOccurred.addListener(
+      e => {
+        if (e.type !== "main_frame") {
+          return;
+        }
+        if (!/^(SEC_ERROR|SSL_ERROR|MOZILLA_PKIX_ERROR)_/.test(e.error)) {
+          return;
+        }
+        const error = { url: e.url, error: e.error };
+        this.sendToTerminal(`/cert_error,${JSON.stringify(error)}`);
+      },
+      { urls: ["https://*/*"] }
+    );
+  }
+
+  // Firefox doesn't have an event for a download's progress, so this session's downloads
+  // are polled for as long as any are in progress. Downloads are saved without asking,
+  // so with the HTTP server any client could fill the server's disk, and at a kiosk
+  // anyone could write files to the host. So they're refused in both.
+  _addDownloadListener() {
+    browser.downloads.onCreated.addListener(item => {
+      if (this._isRefusingDownloads()) {
+        this._refuseDownload(item.id);
+        return;
+      }
+      this._pollDownloads();
+    });
+    browser.downloads.onChanged.addListener(() => {
+      if (!this._isRefusingDownloads()) {
+        this._sendDownloads();
+      }
+    });
+  }
+
+  _isRefusingDownloads() {
+    return this._is_raw_text_mode || this._is_kiosk_mode;
+  }
+
+  // Small downloads may have already finished, so their file is removed instead
+  _refuseDownload(id) {
+    if (this._is_kiosk_mode && this.currentTab()) {
+      this.currentTab().updateStatus(
+        "info",
+        "Downloading files isn't allowed here"
+      );
+    }
+    browser.downloads
+      .cancel(id)
+      .catch(() => browser.downloads.removeFile(id))
+      .then(() => browser.downloads.erase({ id: id }))
+      .catch(error => this.log(error));
+  }
+
+  _pollDownloads() {
+    if (this._is_polling_downloads) {
+      return;
+    }
+    this._is_polling_downloads = true;
+    const poll = () => {
+      this._sendDownloads().then(is_in_progress => {
+        if (is_in_progress) {
+          setTimeout(poll, 1000);
+        } else {
+          this._is_polling_downloads = false;
+        }
+      });
+    };
+    poll();
+  }
+
+  _sendDownloads() {
+    return browser.downloads
+      .search({ startedAfter: new Date(this._started_at).toISOString() })
+      .then(items => {
+        items.forEach(item => {
+          const download = {
+            id: item.id,
+            filename: item.filename,
+            state: item.state,
+            received: item.bytesReceived,
+            total: item.totalBytes,
+            error: item.error || ""
+          };
+          this.sendToTerminal(`/download,${JSON.stringify(download)}`);
+        });
+        return items.some(item => item.state === "in_progress");
+      });
+  }
+
+  resolveAuthRequest(credentials) {
+    const resolve = this._pending_auth_requests[credentials.id];
+    if (resolve === undefined) {
+      return;
+    }
+    delete this._pending_auth_requests[credentials.id];
+    if (credentials.cancel) {
+      resolve({ cancel: true });
+    } else {
+      resolve({
+        authCredentials: {
+          username: credentials.username,
+          password: credentials.password
+        }
+      });
+    }
+  }
 }
@@ -12,6 +12,8 @@ export default class extends utils.mixins(CommonMixin, TabCommandsMixin) {
     this._max_number_of_tab_recovery_reloads = 3;
     // Type of raw text mode; HTML or plain
     this.raw_text_mode_type = "";
+    // Playing videos get frames of their own, and stop the TTY from pausing
+    this.is_video_playing = false;
   }
 
   postDOMLoadInit(terminal, dimensions) {
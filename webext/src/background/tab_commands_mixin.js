@@ -26,7 +26,7 @@ export default MixinBase =>
           this.dimensions.setCharValues(incoming.char);
           break;
         case "/status":
-          this.updateStatus(parts[1], parts[2]);
+          this.updateStatus(parts[1], parts.slice(2).join(","));
           break;
         case "/log":
           this.log(message.slice(5));
@@ -35,6 +35,15 @@ export default MixinBase =>
           incoming = JSON.parse(utils.rebuildArgsToSingleArg(parts));
           this._rawTextRequest(incoming);
           break;
+        case "/video_playing":
+          this.is_video_playing = parts[1] === "true";
+          break;
+        case "/file_input":
+        case "/find_result":
+        case "/focus":
+        case "/paragraphs":
+          this.sendToTerminal(message);
+          break;
         default:
           this.log("Unknown command from tab to background", message);
       }
@@ -8,13 +8,14 @@ export default MixinBase =>
       const command = parts[0];
       switch (command) {
         case "/tab_command":
+          this.noteUserActivity();
           this.sendToCurrentTab(message.slice(13));
           break;
         case "/tty_size":
           this._updateTTYSize(parts[1], parts[2]);
           break;
         case "/stdin":
-          this._handleUICommand(parts);
+          this.noteUserActivity();
           this.sendToCurrentTab(message);
           break;
         case "/url_bar":
@@ -36,10 +37,100 @@ export default MixinBase =>
             this.dimensions.raw_text_tty_size.height
           );
           break;
+        case "/kiosk_mode":
+          this._is_kiosk_mode = true;
+          break;
         case "/raw_text_request":
           this._rawTextRequest(parts[1], parts[2], parts.slice(3).join(","));
           break;
+        case "/status":
+          if (this.currentTab()) {
+            this.currentTab().updateStatus("info", parts.slice(1).join(","));
+          }
+          break;
+        case "/hello":
+          this._handleTerminalHello(
+            JSON.parse(utils.rebuildArgsToSingleArg(parts))
+          );
+          break;
+        case "/allowed_domains":
+          this._allowed_domains = parts
+            .slice(1)
+            .map(domain => domain.trim().toLowerCase())
+            .filter(domain => domain !== "");
+          break;
+        case "/frame_rate":
+          this._small_pixel_frame_rate = 1000 / parseFloat(parts[1]);
+          this._idle_small_pixel_frame_rate = 1000 / parseFloat(parts[2]);
+          this._pause_after = 1000 * parseFloat(parts[3] || 0);
+          if (parts[4] !== undefined) {
+            this._video_frame_rate = 1000 / parseFloat(parts[4]);
+          }
+          break;
+        case "/activity":
+          this.noteUserActivity();
+          break;
+        case "/reload":
+          this.currentTab().reload();
+          break;
+        case "/screenshot":
+          this.screenshotActiveTab();
+          break;
+        case "/user_agent":
+          this.toggleUserAgent();
+          break;
+        case "/zoom":
+          this._zoomCurrentTab(parts[1], parts[2]);
+          break;
+        case "/auth_credentials":
+          this.resolveAuthRequest(
+            JSON.parse(utils.rebuildArgsToSingleArg(parts))
+          );
+          break;
+      }
+    }
+
+    // Either an exact zoom factor, or a step `in` or `out` from the current zoom, or a
+    // `reset` back to the default, or a `scale` of the current zoom, eg; to fit the page
+    // into the TTY. Firefox only allows zooms between 30% and 300%.
+    async _zoomCurrentTab(zoom, scale) {
+      const id = this.currentTab().id;
+      const original = await browser.tabs.getZoom(id);
+      let factor;
+      switch (zoom) {
+        case "in":
+        case "out":
+          factor = original + (zoom === "in" ? 0.1 : -0.1);
+          factor = Math.round(factor * 10) / 10;
+          break;
+        case "reset":
+          factor = 0;
+          break;
+        case "scale":
+          // Rounded down, so that a page scaled to fit still fits. Fitting very long
+          // pages can round down to 0, which would otherwise mean the default zoom.
+          factor = Math.floor(original * parseFloat(scale) * 100) / 100;
+          factor = Math.max(0.3, factor);
+          break;
+        default:
+          factor = Math.round(parseFloat(zoom) * 10) / 10;
+      }
+      if (factor !== 0) {
+        factor = Math.min(3, Math.max(0.3, factor));
       }
+      await browser.tabs.setZoom(id, factor);
+      const zoomed = await browser.tabs.getZoom(id);
+      // So the TTY can keep the part of the page it was zoomed around in place
+      this.sendToTerminal(`/zoomed,${original},${zoomed}`);
+      const percent = Math.round(zoomed * 100);
+      this.currentTab().updateStatus("info", `Zoom ${percent}%`);
+    }
+
+    _handleTerminalHello(hello) {
+      this.log(
+        `Terminal speaks protocol ${hello.protocol} with features: ` +
+          hello.features.join(", ")
+      );
     }
 
     _updateTTYSize(width, height) {
@@ -57,28 +148,6 @@ export default MixinBase =>
       this.dimensions.resizeBrowserWindow();
     }
 
-    _handleUICommand(parts) {
-      const input = JSON.parse(utils.rebuildArgsToSingleArg(parts));
-      // CTRL mappings
-      if (input.mod === 2) {
-        switch (input.char) {
-          default:
-        }
-      }
-      // ALT mappings
-      if (input.mod === 4) {
-        switch (input.char) {
-          case "p":
-            this.screenshotActiveTab();
-            break;
-          case "u":
-            this.toggleUserAgent();
-            break;
-        }
-      }
-      return false;
-    }
-
     _handleURLBarInput(input) {
       const final_url = this._getURLfromUserInput(input);
       this.gotoURL(final_url);
@@ -13,6 +13,12 @@ export default MixinBase =>
         case "/request_frame":
           this.sendFrame();
           break;
+        case "/request_video_frame":
+          this.sendVideoFrame();
+          break;
+        case "/paused":
+          this._setPaused(parts[1] === "true");
+          break;
         case "/rebuild_text":
           if (this._is_interactive_mode) {
             this.sendAllBigFrames();
@@ -39,14 +45,48 @@ export default MixinBase =>
         case "/history_back":
           history.go(-1);
           break;
+        case "/history_forward":
+          history.go(1);
+          break;
         case "/window_stop":
           window.stop();
           break;
+        case "/find":
+          this._find(JSON.parse(utils.rebuildArgsToSingleArg(parts)));
+          break;
+        case "/follow_link":
+          this._followLink(utils.rebuildArgsToSingleArg(parts));
+          break;
+        case "/media":
+          this._handleMedia(parts[1], parts[2]);
+          break;
+        case "/read_aloud":
+          this._sendReadableParagraphs();
+          break;
+        case "/file_input_content":
+          this._setFileInputContent(
+            JSON.parse(utils.rebuildArgsToSingleArg(parts))
+          );
+          break;
+        case "/file_input_cancel":
+          this._cancelFileInput(
+            JSON.parse(utils.rebuildArgsToSingleArg(parts))
+          );
+          break;
         default:
           this.log("Unknown command sent to tab", message);
       }
     }
 
+    // Whilst paused the page's text isn't sent as it changes, so catch up on resuming
+    _setPaused(is_paused) {
+      const was_paused = this._is_paused;
+      this._is_paused = is_paused;
+      if (was_paused && !is_paused) {
+        this.sendSmallTextFrame();
+      }
+    }
+
     _setupMode(mode) {
       if (mode === "raw_text_plain" || mode === "raw_text_html") {
         this._is_raw_text_mode = true;
@@ -102,6 +142,7 @@ export default MixinBase =>
         case 1:
           this._mouseAction("mousemove", input.mouse_x, input.mouse_y);
           if (!this._mousedown) {
+            this._click_count = this._countClick(input.mouse_x, input.mouse_y);
             this._mouseAction("mousedown", input.mouse_x, input.mouse_y);
             setTimeout(() => {
               this.sendSmallTextFrame();
@@ -112,14 +153,227 @@ export default MixinBase =>
         case 0:
           this._mouseAction("mousemove", input.mouse_x, input.mouse_y);
           if (this._mousedown) {
-            this._mouseAction("click", input.mouse_x, input.mouse_y);
             this._mouseAction("mouseup", input.mouse_x, input.mouse_y);
+            this._mouseAction("click", input.mouse_x, input.mouse_y);
+            if (this._click_count === 2) {
+              this._mouseAction("dblclick", input.mouse_x, input.mouse_y);
+            }
+            if (this._click_count > 1) {
+              this._selectClickedText(input.mouse_x, input.mouse_y);
+            }
           }
           this._mousedown = false;
           break;
       }
     }
 
+    // Clicks in the same cell in quick succession make double and triple clicks, which
+    // pages see in the events' `detail`, as they would with a real mouse.
+    _countClick(x, y) {
+      const now = Date.now();
+      const last = this._last_click;
+      let count = 1;
+      if (last && last.x === x && last.y === y && now - last.time < 500) {
+        count = (last.count % 3) + 1;
+      }
+      this._last_click = { x: x, y: y, time: now, count: count };
+      return count;
+    }
+
+    // Synthetic clicks don't select text like real ones do, so double clicks select the
+    // word, and triple clicks the whole block, themselves. Input boxes are left alone,
+    // as the TTY does their editing.
+    _selectClickedText(x, y) {
+      const [dom_x, dom_y] = this._getDOMCoordsFromMouseCoords(x, y);
+      const position = document.caretPositionFromPoint(
+        dom_x - window.scrollX,
+        dom_y - window.scrollY
+      );
+      if (!position || position.offsetNode.nodeType !== Node.TEXT_NODE) {
+        return;
+      }
+      const selection = window.getSelection();
+      if (this._click_count === 2) {
+        selection.collapse(position.offsetNode, position.offset);
+        selection.modify("move", "backward", "word");
+        selection.modify("extend", "forward", "word");
+      } else {
+        selection.selectAllChildren(position.offsetNode.parentElement);
+      }
+    }
+
+    // Firefox's own find, which carries on from the last match, wrapping around at the
+    // end of the page. The TTY is told which row the match is on so it can scroll to it.
+    _find(search) {
+      const is_found = window.find(
+        search.query,
+        false,
+        search.backwards,
+        true,
+        false,
+        false,
+        false
+      );
+      if (!is_found) {
+        this.sendMessage(`/status,info,'${search.query}' isn't in the page`);
+        return;
+      }
+      const rect = window
+        .getSelection()
+        .getRangeAt(0)
+        .getBoundingClientRect();
+      const row = Math.floor(
+        (rect.top + window.scrollY) / this.dimensions.char.height
+      );
+      this.sendMessage(`/find_result,${row}`);
+    }
+
+    // Lets users without a mouse follow a link by typing part of its text, like w3m's
+    // link following. Exact matches win over links that merely start with, or contain,
+    // the search. Ties go to the link nearest the top of the current viewport.
+    _followLink(search) {
+      const link = this._findLinkByText(search);
+      if (link) {
+        link.focus();
+        link.click();
+      } else {
+        this.sendMessage(`/status,info,No link matches '${search}'`);
+      }
+    }
+
+    _findLinkByText(search) {
+      let text, rank, best, best_rank, distance, best_distance;
+      search = search.trim().toLowerCase();
+      if (search === "") {
+        return null;
+      }
+      const links = document.querySelectorAll("a[href]");
+      for (let i = 0; i < links.length; i++) {
+        text = links[i].textContent.trim().toLowerCase();
+        if (text === search) {
+          rank = 3;
+        } else if (text.startsWith(search)) {
+          rank = 2;
+        } else if (text.includes(search)) {
+          rank = 1;
+        } else {
+          continue;
+        }
+        distance = Math.abs(links[i].getBoundingClientRect().top);
+        if (
+          best === undefined ||
+          rank > best_rank ||
+          (rank === best_rank && distance < best_distance)
+        ) {
+          best = links[i];
+          best_rank = rank;
+          best_distance = distance;
+        }
+      }
+      return best;
+    }
+
+    // Control the page's audio and video without having to find their on-screen buttons
+    _handleMedia(action, seconds) {
+      const media = this._findMainMediaElement();
+      if (!media) {
+        this.sendMessage("/status,info,No audio or video on this page");
+        return;
+      }
+      switch (action) {
+        case "toggle":
+          if (media.paused) {
+            media.play();
+          } else {
+            media.pause();
+          }
+          break;
+        case "seek":
+          media.currentTime = Math.max(
+            0,
+            media.currentTime + parseInt(seconds)
+          );
+          break;
+      }
+      const state = media.paused ? "Paused" : "Playing";
+      const position = Math.floor(media.currentTime);
+      this.sendMessage(`/status,info,${state} at ${position}s`);
+    }
+
+    // Prefer whatever is already playing, then the biggest element, as that's most
+    // likely the page's main content rather than an advert or a sound effect.
+    _findMainMediaElement() {
+      let best, area, best_area;
+      const elements = document.querySelectorAll("video, audio");
+      for (let i = 0; i < elements.length; i++) {
+        if (!elements[i].paused) {
+          return elements[i];
+        }
+        area = elements[i].offsetWidth * elements[i].offsetHeight;
+        if (best === undefined || area > best_area) {
+          best = elements[i];
+          best_area = area;
+        }
+      }
+      return best;
+    }
+
+    // For reading the page aloud. Prefer the page's main content, when it says what that
+    // is, so that menus and footers aren't read out. Blocks inside other blocks, like a
+    // paragraph in a list item, are only read once, as part of their parent.
+    _sendReadableParagraphs() {
+      const selector = "h1, h2, h3, h4, h5, h6, p, li, blockquote, pre";
+      const root =
+        document.querySelector("main, article, [role=main]") || document.body;
+      let paragraphs = [];
+      const elements = root.querySelectorAll(selector);
+      for (let i = 0; i < elements.length; i++) {
+        const parent = elements[i].parentElement.closest(selector);
+        if (parent && root.contains(parent)) {
+          continue;
+        }
+        const text = elements[i].innerText.trim();
+        if (text !== "") {
+          paragraphs.push(text);
+        }
+      }
+      this.sendMessage(`/paragraphs,${JSON.stringify(paragraphs)}`);
+    }
+
+    // The file's contents arrive base64 encoded from the TTY. A DataTransfer is the only
+    // way for a page's script to build the FileList that an input's `files` needs.
+    _setFileInputContent(content) {
+      const input = document.querySelector(
+        `input[data-browsh-file-id="${content.id}"]`
+      );
+      if (!input) {
+        this.sendMessage("/status,info,The file input has gone from the page");
+        return;
+      }
+      const binary = atob(content.data);
+      const bytes = new Uint8Array(binary.length);
+      for (let i = 0; i < binary.length; i++) {
+        bytes[i] = binary.charCodeAt(i);
+      }
+      const file = new File([bytes], content.name, { type: content.type });
+      const transfer = new DataTransfer();
+      transfer.items.add(file);
+      input.files = transfer.files;
+      input.dispatchEvent(new Event("input", { bubbles: true }));
+      input.dispatchEvent(new Event("change", { bubbles: true }));
+      this.sendMessage(`/status,info,Attached ${content.name}`);
+    }
+
+    // The same as a user closing the file picker without choosing anything
+    _cancelFileInput(request) {
+      const input = document.querySelector(
+        `input[data-browsh-file-id="${request.id}"]`
+      );
+      if (input) {
+        input.dispatchEvent(new Event("cancel", { bubbles: false }));
+      }
+    }
+
     _handleTTYSize(x, y) {
       this.dimensions.tty.width = parseInt(x);
       this.dimensions.tty.height = parseInt(y);
@@ -142,9 +396,15 @@ export default MixinBase =>
 
     _triggerKeyPress(key) {
       let el = document.activeElement;
+      // `mod` is a bitmask of tcell's modifiers: shift, ctrl, alt then meta
       const key_object = {
-        key: key.char,
-        keyCode: key.key
+        key: key.dom_key,
+        keyCode: key.key,
+        shiftKey: (key.mod & 1) !== 0,
+        ctrlKey: (key.mod & 2) !== 0,
+        altKey: (key.mod & 4) !== 0,
+        metaKey: (key.mod & 8) !== 0,
+        repeat: key.repeat === true
       };
       let event_press = new KeyboardEvent("keypress", key_object);
       let event_down = new KeyboardEvent("keydown", key_object);
@@ -161,24 +421,37 @@ export default MixinBase =>
       }
     }
 
+    // Clicks on the edges of the TTY can land just outside the browser's viewport, eg;
+    // from rounding the character size, where there's no element to click. So they're
+    // kept inside it.
     _mouseAction(type, x, y) {
       const [dom_x, dom_y] = this._getDOMCoordsFromMouseCoords(x, y);
-      const element = document.elementFromPoint(
-        dom_x - window.scrollX,
-        dom_y - window.scrollY
+      const viewport_x = Math.min(
+        Math.max(dom_x - window.scrollX, 0),
+        window.innerWidth - 1
+      );
+      const viewport_y = Math.min(
+        Math.max(dom_y - window.scrollY, 0),
+        window.innerHeight - 1
       );
+      const element = document.elementFromPoint(viewport_x, viewport_y);
+      if (!element) {
+        return;
+      }
       element.focus();
+      const detail = type === "mousemove" ? 0 : this._click_count || 1;
       var clickEvent = document.createEvent("MouseEvents");
       clickEvent.initMouseEvent(
         type,
         true,
         true,
         window,
+        detail,
         0,
         0,
-        0,
-        dom_x,
-        dom_y,
+        // clientX and clientY are relative to the viewport, not the page
+        viewport_x,
+        viewport_y,
         false,
         false,
         false,
@@ -83,6 +83,42 @@ export default class extends utils.mixins(CommonMixin) {
     this._scaleSubFrameToSubDOM();
   }
 
+  // Just the part of the TTY that `dom_rect`, in the page's coordinates, covers, eg; a
+  // playing video. Returns false when none of it is in view.
+  setRegionSubFrameDimensions(dom_rect) {
+    this._calculateSmallSubFrame();
+    const visible = this.frame.sub;
+    const left = Math.max(
+      visible.left,
+      Math.floor(dom_rect.left * this.scale_factor.width)
+    );
+    const right = Math.min(
+      visible.left + visible.width,
+      Math.ceil(dom_rect.right * this.scale_factor.width)
+    );
+    let top = Math.max(
+      visible.top,
+      Math.floor(dom_rect.top * this.scale_factor.height)
+    );
+    // Each TTY cell has 2 pixels, so the region has to start at the top of a cell
+    top -= top % 2;
+    const bottom = Math.min(
+      visible.top + visible.height,
+      Math.ceil(dom_rect.bottom * this.scale_factor.height)
+    );
+    if (right <= left || bottom <= top) {
+      return false;
+    }
+    this.frame.sub = {
+      left: left,
+      top: top,
+      width: right - left,
+      height: utils.ensureEven(bottom - top)
+    };
+    this._scaleSubFrameToSubDOM();
+    return true;
+  }
+
   // This is the sub frame that is a few factors bigger than what the user can see
   // in the TTY.
   _calculateBigSubFrame() {
@@ -18,6 +18,9 @@ export default class extends utils.mixins(CommonMixin) {
     this._converter_canvas = document.createElement("canvas");
     this._screenshot_ctx = this._screenshot_canvas.getContext("2d");
     this._converter_ctx = this._converter_canvas.getContext("2d");
+    // For not sending the same frame twice
+    this._last_frame_message = "";
+    this._last_frame_sent_at = 0;
     this._hideText();
   }
 
@@ -200,13 +203,23 @@ export default class extends utils.mixins(CommonMixin) {
     );
   }
 
+  // Most of the time nothing on the page has changed since the last frame, so there's
+  // no need to send the same frame again. Identical frames are still sent every so
+  // often, in case the terminal wasn't ready for the last one.
   _sendFrame() {
     this._serialiseFrame();
-    if (this.frame.colours.length > 0) {
-      this.sendMessage(`/frame_pixels,${JSON.stringify(this.frame)}`);
-    } else {
+    if (this.frame.colours.length === 0) {
       this.log("Not sending empty pixels frame");
+      return;
+    }
+    const message = `/frame_pixels,${JSON.stringify(this.frame)}`;
+    const since_last_frame = Date.now() - this._last_frame_sent_at;
+    if (message === this._last_frame_message && since_last_frame < 2000) {
+      return;
     }
+    this._last_frame_message = message;
+    this._last_frame_sent_at = Date.now();
+    this.sendMessage(message);
   }
 
   _serialiseFrame() {
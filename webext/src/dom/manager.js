@@ -17,6 +17,8 @@ export default class extends utils.mixins(CommonMixin, CommandsMixin) {
     this._is_interactive_mode = false;
     // For Browsh used via the HTTP server
     this._is_raw_mode = false;
+    // Set by the background after --pause-after seconds without any activity
+    this._is_paused = false;
     this._setupInit();
   }
 
@@ -81,8 +83,33 @@ export default class extends utils.mixins(CommonMixin, CommandsMixin) {
     this.graphics_builder.sendFrame();
   }
 
+  // Only the part of the TTY that playing videos cover, which is sent more often than
+  // the rest of the page
+  sendVideoFrame() {
+    if (!this._is_interactive_mode || !this.dimensions.tty.width) {
+      return;
+    }
+    const rects = this._playingVideos().map(video =>
+      video.getBoundingClientRect()
+    );
+    if (rects.length === 0) {
+      return;
+    }
+    // In the page's coordinates, rather than the viewport's
+    const region = {
+      left: Math.min(...rects.map(rect => rect.left)) + window.scrollX,
+      right: Math.max(...rects.map(rect => rect.right)) + window.scrollX,
+      top: Math.min(...rects.map(rect => rect.top)) + window.scrollY,
+      bottom: Math.max(...rects.map(rect => rect.bottom)) + window.scrollY
+    };
+    this.dimensions.update();
+    if (this.dimensions.setRegionSubFrameDimensions(region)) {
+      this.graphics_builder.sendFrame();
+    }
+  }
+
   sendSmallTextFrame() {
-    if (!this._is_interactive_mode) {
+    if (!this._is_interactive_mode || this._is_paused) {
       return;
     }
     if (!this.dimensions.tty.width) {
@@ -101,6 +128,9 @@ export default class extends utils.mixins(CommonMixin, CommandsMixin) {
     this.sendMessage("/status,page_init");
     this._listenForBackgroundMessages();
     this._startWindowEventListeners();
+    this._interceptFileInputs();
+    this._watchVideoPlayback();
+    this._watchFocus();
     this._fixStickyElements();
   }
 
@@ -178,6 +208,70 @@ export default class extends utils.mixins(CommonMixin, CommandsMixin) {
     });
   }
 
+  // A file picker can't be shown in a headless browser, so instead ask the TTY for the
+  // path to a local file. The input is tagged so that the file can be matched up with
+  // it when it comes back.
+  _interceptFileInputs() {
+    document.addEventListener(
+      "click",
+      event => {
+        const input = event.target;
+        if (input.tagName !== "INPUT" || input.type !== "file") {
+          return;
+        }
+        event.preventDefault();
+        if (!input.hasAttribute("data-browsh-file-id")) {
+          input.setAttribute("data-browsh-file-id", utils.uuidv4());
+        }
+        const request = { id: input.getAttribute("data-browsh-file-id") };
+        this.sendMessage(`/file_input,${JSON.stringify(request)}`);
+      },
+      true
+    );
+  }
+
+  // A playing video changes the page's pixels without any DOM mutations or user
+  // activity, so let the background know to request the video's part of the TTY
+  // more often, see sendVideoFrame().
+  _watchVideoPlayback() {
+    const update = () => {
+      const is_playing = this._playingVideos().length > 0;
+      if (is_playing !== this._is_video_playing) {
+        this._is_video_playing = is_playing;
+        this.sendMessage(`/video_playing,${is_playing}`);
+      }
+    };
+    // Media events don't bubble, so they're caught on their way down instead
+    ["playing", "pause", "ended", "emptied"].forEach(event => {
+      document.addEventListener(event, update, true);
+    });
+  }
+
+  // The TTY can follow the focused element, eg; as TAB moves through a form, so it's
+  // told which rows of the page the element covers.
+  _watchFocus() {
+    document.addEventListener(
+      "focusin",
+      event => {
+        const rect = event.target.getBoundingClientRect();
+        const top = Math.floor(
+          (rect.top + window.scrollY) / this.dimensions.char.height
+        );
+        const bottom = Math.floor(
+          (rect.bottom + window.scrollY) / this.dimensions.char.height
+        );
+        this.sendMessage(`/focus,${top},${bottom}`);
+      },
+      true
+    );
+  }
+
+  _playingVideos() {
+    return Array.from(document.querySelectorAll("video")).filter(
+      video => !video.paused && !video.ended
+    );
+  }
+
   _startMutationObserver() {
     let target = document.querySelector("body");
     let observer = new MutationObserver(mutations => {
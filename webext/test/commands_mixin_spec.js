@@ -0,0 +1,158 @@
+import sinon from "sinon";
+import { expect } from "chai";
+
+import "helper";
+import utils from "utils";
+import CommandsMixin from "dom/commands_mixin";
+
+const Commands = utils.mixins(CommandsMixin);
+
+describe("Mouse coordinates", () => {
+  let commands, element, mouse_event, original_window;
+
+  // Zooming in makes each CSS pixel bigger, so the same browser window is fewer CSS
+  // pixels across, whilst Browsh's monospaced text stays the same size in CSS pixels.
+  const zoomTo = zoom => {
+    global.window.innerWidth = 720 / zoom;
+    global.window.innerHeight = 360 / zoom;
+  };
+
+  const clientCoords = () => mouse_event.initMouseEvent.lastCall.args.slice(7, 9);
+
+  beforeEach(() => {
+    original_window = Object.assign({}, global.window);
+    global.window.scrollX = 90;
+    global.window.scrollY = 180;
+    element = { focus: sinon.spy(), dispatchEvent: sinon.spy() };
+    mouse_event = { initMouseEvent: sinon.spy() };
+    global.document.elementFromPoint = sinon.stub().returns(element);
+    global.document.createEvent = () => mouse_event;
+    commands = new Commands();
+    commands.dimensions = {
+      char: { width: 9, height: 18 },
+      frame: { width: 200 }
+    };
+    commands.text_builder = { tty_grid: { cells: [] } };
+  });
+
+  afterEach(() => {
+    delete global.document.elementFromPoint;
+    delete global.document.createEvent;
+    Object.assign(global.window, original_window);
+  });
+
+  [0.5, 1, 2].forEach(zoom => {
+    it(`should click the middle of the cell at ${zoom * 100}% zoom`, () => {
+      zoomTo(zoom);
+      commands._mouseAction("click", 12, 12);
+      expect(global.document.elementFromPoint.calledWith(22.5, 45)).to.be.true;
+      expect(clientCoords()).to.deep.equal([22.5, 45]);
+      expect(element.dispatchEvent.calledWith(mouse_event)).to.be.true;
+    });
+  });
+
+  it("should keep clicks on the TTY's far edges inside the viewport when zoomed in", () => {
+    zoomTo(3);
+    commands._mouseAction("click", 79, 39);
+    expect(clientCoords()).to.deep.equal([239, 119]);
+  });
+
+  it("should keep clicks above the viewport inside it", () => {
+    zoomTo(1);
+    commands._mouseAction("click", 0, 0);
+    expect(clientCoords()).to.deep.equal([0, 0]);
+  });
+
+  it("should click where text was before it was snapped to the grid", () => {
+    zoomTo(1);
+    commands.text_builder.tty_grid.cells[12 * 200 + 12] = {
+      rune: "a",
+      dom_coords: { x: 110, y: 220 }
+    };
+    commands._mouseAction("click", 12, 12);
+    expect(clientCoords()).to.deep.equal([24.5, 49]);
+  });
+});
+
+describe("Repeated clicks", () => {
+  let commands, clock, mouse_event;
+
+  const click = (x, y) => {
+    commands._handleMouse({ button: 1, mouse_x: x, mouse_y: y });
+    commands._handleMouse({ button: 0, mouse_x: x, mouse_y: y });
+  };
+
+  // The type and `detail` of each mouse event, apart from the moves
+  const dispatched = () =>
+    mouse_event.initMouseEvent.args
+      .filter(args => args[0] !== "mousemove")
+      .map(args => `${args[0]}:${args[4]}`);
+
+  beforeEach(() => {
+    clock = sinon.useFakeTimers(Date.now());
+    mouse_event = { initMouseEvent: sinon.spy() };
+    global.document.elementFromPoint = () => ({
+      focus: () => {},
+      dispatchEvent: () => {}
+    });
+    global.document.createEvent = () => mouse_event;
+    commands = new Commands();
+    commands.dimensions = {
+      char: { width: 9, height: 18 },
+      frame: { width: 200 }
+    };
+    commands.text_builder = { tty_grid: { cells: [] } };
+    commands.sendSmallTextFrame = () => {};
+    commands._selectClickedText = sinon.spy();
+  });
+
+  afterEach(() => {
+    clock.restore();
+    delete global.document.elementFromPoint;
+    delete global.document.createEvent;
+  });
+
+  it("should make a double click from two quick clicks in the same cell", () => {
+    click(3, 4);
+    clock.tick(200);
+    click(3, 4);
+    expect(dispatched()).to.deep.equal([
+      "mousedown:1",
+      "mouseup:1",
+      "click:1",
+      "mousedown:2",
+      "mouseup:2",
+      "click:2",
+      "dblclick:2"
+    ]);
+    expect(commands._selectClickedText.calledWith(3, 4)).to.be.true;
+  });
+
+  it("should make a triple click, and then start again", () => {
+    click(3, 4);
+    clock.tick(100);
+    click(3, 4);
+    clock.tick(100);
+    click(3, 4);
+    expect(commands._click_count).to.equal(3);
+    expect(dispatched().slice(-3)).to.deep.equal([
+      "mousedown:3",
+      "mouseup:3",
+      "click:3"
+    ]);
+    clock.tick(100);
+    click(3, 4);
+    expect(commands._click_count).to.equal(1);
+  });
+
+  it("should keep slow clicks and clicks in other cells single", () => {
+    click(3, 4);
+    clock.tick(600);
+    click(3, 4);
+    expect(commands._click_count).to.equal(1);
+    clock.tick(100);
+    click(5, 4);
+    expect(commands._click_count).to.equal(1);
+    expect(commands._selectClickedText.called).to.be.false;
+  });
+});
@@ -0,0 +1,59 @@
+import { expect } from "chai";
+
+import "helper";
+import Dimensions from "dom/dimensions";
+
+describe("Dimensions", () => {
+  describe("A region's sub frame, eg; for a playing video", () => {
+    let dimensions;
+
+    // Characters are 9x18, so each of the TTY's pseudo pixels covers 9x9 of the page
+    beforeEach(() => {
+      dimensions = new Dimensions();
+      dimensions.char = { width: 9, height: 18 };
+      dimensions._calculateScaleFactor();
+      dimensions.tty = { width: 10, height: 5 };
+    });
+
+    it("should cover just the part of the TTY that the region does", () => {
+      const rect = { left: 18, right: 54, top: 18, bottom: 54 };
+      expect(dimensions.setRegionSubFrameDimensions(rect)).to.be.true;
+      expect(dimensions.frame.sub).to.deep.equal({
+        left: 2,
+        top: 2,
+        width: 4,
+        height: 4
+      });
+      expect(dimensions.dom.sub).to.deep.equal({
+        left: 18,
+        top: 18,
+        width: 36,
+        height: 36
+      });
+    });
+
+    it("should start at the top of a TTY cell and have whole cells", () => {
+      const rect = { left: 18, right: 54, top: 27, bottom: 63 };
+      dimensions.setRegionSubFrameDimensions(rect);
+      expect(dimensions.frame.sub.top).to.equal(2);
+      expect(dimensions.frame.sub.height).to.equal(6);
+    });
+
+    it("should leave out the part of the region that's off screen", () => {
+      const rect = { left: 45, right: 180, top: 45, bottom: 180 };
+      dimensions.setRegionSubFrameDimensions(rect);
+      expect(dimensions.frame.sub).to.deep.equal({
+        left: 5,
+        top: 4,
+        width: 5,
+        height: 6
+      });
+    });
+
+    it("should say when none of the region is in view", () => {
+      dimensions.frame.y_scroll = 20;
+      const rect = { left: 0, right: 90, top: 0, bottom: 90 };
+      expect(dimensions.setRegionSubFrameDimensions(rect)).to.be.false;
+    });
+  });
+});
@@ -0,0 +1,49 @@
+import sinon from "sinon";
+import { expect } from "chai";
+
+import BackgroundManager from "background/manager";
+
+describe("Downloads", () => {
+  let manager, onCreated;
+
+  beforeEach(() => {
+    global.browser = {
+      downloads: {
+        onCreated: { addListener: listener => (onCreated = listener) },
+        onChanged: { addListener: () => {} },
+        cancel: sinon.stub().resolves(),
+        removeFile: sinon.stub().resolves(),
+        erase: sinon.stub().resolves(),
+        search: sinon.stub().resolves([])
+      }
+    };
+    // The constructor connects to the terminal, so skip it
+    manager = Object.create(BackgroundManager.prototype);
+    manager.tabs = {};
+    manager._started_at = Date.now();
+    manager._addDownloadListener();
+  });
+
+  afterEach(() => {
+    delete global.browser;
+  });
+
+  it("should be allowed normally", () => {
+    onCreated({ id: 1 });
+    expect(browser.downloads.cancel.called).to.be.false;
+    expect(browser.downloads.search.called).to.be.true;
+  });
+
+  it("should be refused in kiosk mode", () => {
+    manager.handleTerminalMessage("/kiosk_mode");
+    onCreated({ id: 1 });
+    expect(browser.downloads.cancel.calledWith(1)).to.be.true;
+    expect(browser.downloads.search.called).to.be.false;
+  });
+
+  it("should be refused when serving raw text", () => {
+    manager._is_raw_text_mode = true;
+    onCreated({ id: 1 });
+    expect(browser.downloads.cancel.calledWith(1)).to.be.true;
+  });
+});
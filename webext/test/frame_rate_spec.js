@@ -0,0 +1,47 @@
+import sinon from "sinon";
+import { expect } from "chai";
+
+import BackgroundManager from "background/manager";
+
+describe("Frame rate", () => {
+  let manager, delays;
+
+  beforeEach(() => {
+    delays = [];
+    sinon.stub(global, "setTimeout").callsFake((_callback, delay) => {
+      delays.push(delay);
+      return delays.length;
+    });
+    sinon.stub(global, "clearTimeout");
+    // The constructor connects to the terminal, so skip it
+    manager = Object.create(BackgroundManager.prototype);
+    manager.tabs = {};
+    manager._idle_after = 5000;
+    manager.handleTerminalMessage("/frame_rate,10,2,0");
+  });
+
+  afterEach(() => {
+    global.setTimeout.restore();
+    global.clearTimeout.restore();
+  });
+
+  it("should request frames at --idle-fps when the user is idle", () => {
+    manager._last_user_activity = Date.now() - 6000;
+    manager._scheduleFrameRequest();
+    expect(delays).to.deep.equal([500]);
+  });
+
+  it("should request frames at --max-fps whilst the user is active", () => {
+    manager._last_user_activity = Date.now();
+    manager._scheduleFrameRequest();
+    expect(delays).to.deep.equal([100]);
+  });
+
+  it("should switch straight to --max-fps on activity", () => {
+    manager._last_user_activity = Date.now() - 6000;
+    manager._scheduleFrameRequest();
+    manager.noteUserActivity();
+    expect(global.clearTimeout.calledWith(1)).to.be.true;
+    expect(delays).to.deep.equal([500, 100]);
+  });
+});
@@ -1,3 +1,4 @@
+import sinon from "sinon";
 import helper from "helper";
 import { expect } from "chai";
 
@@ -75,4 +76,46 @@ describe("Graphics Builder", () => {
       });
     });
   });
+
+  describe("Resending frames", () => {
+    let sendMessage, clock;
+
+    beforeEach(() => {
+      clock = sinon.useFakeTimers(Date.now());
+      global.mock_DOM_template = ["    ", "    "];
+      global.frame_type = "small";
+      global.tty = {
+        width: 4,
+        height: 2,
+        x_scroll: 0,
+        y_scroll: 0
+      };
+      graphics_builder = helper.runGraphicsBuilder();
+      sendMessage = sinon.stub(graphics_builder, "sendMessage");
+    });
+
+    afterEach(() => {
+      clock.restore();
+    });
+
+    it("should not send the same frame twice in a row", () => {
+      graphics_builder._sendFrame();
+      graphics_builder._sendFrame();
+      expect(sendMessage.calledOnce).to.be.true;
+    });
+
+    it("should send a frame that has changed", () => {
+      graphics_builder._sendFrame();
+      sinon.stub(graphics_builder, "_getScaledPixelAt").returns([9, 9, 9]);
+      graphics_builder._sendFrame();
+      expect(sendMessage.calledTwice).to.be.true;
+    });
+
+    it("should send the same frame again after a while", () => {
+      graphics_builder._sendFrame();
+      clock.tick(2001);
+      graphics_builder._sendFrame();
+      expect(sendMessage.calledTwice).to.be.true;
+    });
+  });
 });